@@ -0,0 +1,721 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ssh-vom/boox-serve/internal/app"
+	"github.com/ssh-vom/boox-serve/internal/boox"
+	"github.com/ssh-vom/boox-serve/internal/config"
+	"github.com/ssh-vom/boox-serve/internal/imaging"
+	"github.com/ssh-vom/boox-serve/internal/providers/manga"
+	"github.com/ssh-vom/boox-serve/internal/providers/manga/mangadex"
+)
+
+const (
+	exitOK          = 0
+	exitError       = 1
+	exitUsage       = 2
+	exitUnreachable = 3
+	exitPartial     = 4
+)
+
+var (
+	errUsage             = errors.New("usage error")
+	errDeviceUnreachable = errors.New("boox device unreachable")
+	errInterrupted       = errors.New("interrupted")
+)
+
+type cliCommand struct {
+	name    string
+	usage   string
+	summary string
+	run     func(ctx context.Context, cli *cliContext, args []string) error
+}
+
+type cliContext struct {
+	cfg        config.Config
+	httpClient *http.Client
+	stdout     io.Writer
+	stderr     io.Writer
+}
+
+type searchOutput struct {
+	ID       string `json:"id"`
+	Title    string `json:"title"`
+	CoverURL string `json:"cover_url,omitempty"`
+}
+
+type chapterOutput struct {
+	ID       string `json:"id"`
+	Number   string `json:"number"`
+	Title    string `json:"title,omitempty"`
+	Volume   string `json:"volume,omitempty"`
+	Language string `json:"language,omitempty"`
+	Group    string `json:"group,omitempty"`
+	Uploader string `json:"uploader,omitempty"`
+	Pages    int    `json:"pages,omitempty"`
+	Label    string `json:"label"`
+
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+}
+
+type syncOutput struct {
+	Actions   []app.SyncAction `json:"actions"`
+	Unchanged int              `json:"unchanged"`
+	Applied   bool             `json:"applied"`
+	Error     string           `json:"error,omitempty"`
+}
+
+type pushOutput struct {
+	MangaID  string          `json:"manga_id"`
+	Title    string          `json:"title"`
+	Chapters []chapterOutput `json:"chapters"`
+	Uploaded []chapterOutput `json:"uploaded"`
+	Existing []chapterOutput `json:"existing"`
+	Skipped  []chapterOutput `json:"skipped"`
+	Aborted  []chapterOutput `json:"aborted"`
+	Error    string          `json:"error,omitempty"`
+}
+
+func cliCommands() []cliCommand {
+	return []cliCommand{
+		{name: "search", usage: "search [--json] [--rating r,...] [--status s,...] [--demographic d,...] [--original-language ja,...] [--tag t,...] [--exclude-tag t,...] [--year N] [--sort order] [--offset N] [query]", summary: "Search MangaDex for a title", run: runSearch},
+		{name: "chapters", usage: "chapters [--json] [--languages es,en] [--dedupe all|best] [--group name,...] <manga-id>", summary: "List chapters available for a manga", run: runChapters},
+		{name: "push", usage: "push [--json] [--title name] [--folder path] [--chapters 1-10] [--languages es,en] [--dedupe all|best] [--group name,...] [--existing skip|overwrite|copy] [--bundle chapter|volume|N] [--format cbz|epub|pdf] [--target boox|local|both] [--local-dir path] [--transcode] [--optimize] [--grayscale] [--quality N] [--spreads off|split|rotate] [--crop] <manga-id>", summary: "Download chapters and upload them to the Boox", run: runPush},
+		{name: "sync", usage: "sync [--json] [--dry-run] [--delete] [--folder path] <local-dir>", summary: "Mirror a local folder tree onto the Boox", run: runSync},
+		{name: "device", usage: "device [--json]", summary: "Check the Boox connection and print device details", run: runDevice},
+	}
+}
+
+func runCLI(cfg config.Config, httpClient *http.Client, args []string) int {
+	cli := &cliContext{cfg: cfg, httpClient: httpClient, stdout: os.Stdout, stderr: os.Stderr}
+
+	if len(args) == 0 || args[0] == "help" {
+		printCLIUsage(cli.stdout)
+		return exitOK
+	}
+
+	var command *cliCommand
+	for _, candidate := range cliCommands() {
+		if candidate.name == args[0] {
+			candidate := candidate
+			command = &candidate
+			break
+		}
+	}
+	if command == nil {
+		fmt.Fprintf(cli.stderr, "Unknown command %q\n\n", args[0])
+		printCLIUsage(cli.stderr)
+		return exitUsage
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err := command.run(ctx, cli, args[1:])
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.Is(err, errUsage):
+		fmt.Fprintf(cli.stderr, "%v\nUsage: boox-serve %s\n", err, command.usage)
+		return exitUsage
+	case errors.Is(err, errDeviceUnreachable):
+		fmt.Fprintf(cli.stderr, "Error: %v\n", err)
+		return exitUnreachable
+	case errors.Is(err, manga.ErrChapterMetadataMissing), errors.Is(err, manga.ErrChapterNoPages), errors.Is(err, errInterrupted):
+		fmt.Fprintf(cli.stderr, "Error: %v\n", err)
+		return exitPartial
+	default:
+		fmt.Fprintf(cli.stderr, "Error: %v\n", err)
+		return exitError
+	}
+}
+
+func printCLIUsage(writer io.Writer) {
+	fmt.Fprintln(writer, "Usage: boox-serve [--verbose] [command] [flags]")
+	fmt.Fprintln(writer, "")
+	fmt.Fprintln(writer, "Run without a command to start the TUI.")
+	fmt.Fprintln(writer, "")
+	fmt.Fprintln(writer, "Commands:")
+	for _, command := range cliCommands() {
+		fmt.Fprintf(writer, "  %-60s %s\n", command.usage, command.summary)
+	}
+	fmt.Fprintln(writer, "")
+	fmt.Fprintln(writer, "Exit codes: 0 success, 1 error, 2 usage, 3 device unreachable, 4 some chapters skipped")
+}
+
+func runSearch(ctx context.Context, cli *cliContext, args []string) error {
+	flags := newFlagSet("search", cli.stderr)
+	jsonOutput := flags.Bool("json", false, "print JSON output")
+	ratings := flags.String("rating", strings.Join(cli.cfg.Providers.ContentRatings, ","), "content ratings: safe, suggestive, erotica, pornographic")
+	statuses := flags.String("status", "", "publication status: ongoing, completed, hiatus, cancelled")
+	demographics := flags.String("demographic", "", "demographic: shounen, shoujo, seinen, josei, none")
+	languages := flags.String("original-language", "", "original languages, e.g. ja,ko")
+	tags := flags.String("tag", "", "tags the series must have, e.g. action,romance")
+	excludedTags := flags.String("exclude-tag", "", "tags the series must not have")
+	year := flags.Int("year", 0, "publication year")
+	offset := flags.Int("offset", 0, "number of results to skip")
+	orderSpec := flags.String("sort", string(manga.OrderRelevance), "sort order: relevance, latest, popular, rating, newest, title or year")
+	positional, err := parseInterspersed(flags, args)
+	if err != nil {
+		return err
+	}
+
+	if *offset < 0 {
+		return fmt.Errorf("%w: --offset must not be negative", errUsage)
+	}
+
+	order, err := manga.ParseSearchOrder(*orderSpec)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	filter := manga.SearchFilter{
+		ContentRatings:    manga.ParseList(*ratings),
+		Statuses:          manga.ParseList(*statuses),
+		Demographics:      manga.ParseList(*demographics),
+		OriginalLanguages: manga.ParseList(*languages),
+		IncludedTags:      manga.ParseList(*tags),
+		ExcludedTags:      manga.ParseList(*excludedTags),
+		Year:              *year,
+		Order:             order,
+	}
+	if err := filter.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+
+	query := strings.TrimSpace(strings.Join(positional, " "))
+	if query == "" && !filter.Active() {
+		return fmt.Errorf("%w: search query cannot be empty", errUsage)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 20*time.Second)
+	defer cancel()
+
+	provider := newMangaDex(cli, "")
+	page, err := provider.Search(ctx, query, filter, *offset)
+	if err != nil {
+		return err
+	}
+	if page.HasMore() {
+		fmt.Fprintf(cli.stderr, "Showing %d-%d of %d; pass --offset %d for more\n", *offset+1, page.Next, page.Total, page.Next)
+	}
+
+	if *jsonOutput {
+		output := make([]searchOutput, 0, len(page.Results))
+		for _, result := range page.Results {
+			output = append(output, searchOutput{ID: result.ID, Title: result.Title, CoverURL: result.CoverURL})
+		}
+		return writeJSON(cli.stdout, output)
+	}
+
+	for _, result := range page.Results {
+		fmt.Fprintf(cli.stdout, "%s\t%s\n", result.ID, result.Title)
+	}
+	return nil
+}
+
+func runChapters(ctx context.Context, cli *cliContext, args []string) error {
+	flags := newFlagSet("chapters", cli.stderr)
+	jsonOutput := flags.Bool("json", false, "print JSON output")
+	languages := flags.String("languages", strings.Join(cli.cfg.Providers.ChapterLanguages, ","), "preferred chapter languages, e.g. es,en")
+	dedupeSpec := flags.String("dedupe", string(cli.cfg.Providers.Dedupe), "duplicate uploads of a chapter: all or best")
+	groups := flags.String("group", "", "preferred scanlation groups, e.g. group-a,group-b (defaults to the series setting)")
+	positional, err := parseInterspersed(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("%w: expected exactly one manga id", errUsage)
+	}
+
+	dedupeMode, err := manga.ParseDedupeMode(*dedupeSpec)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	provider := newMangaDex(cli, *languages)
+	chapters, err := provider.FetchChapters(ctx, positional[0])
+	if err != nil {
+		return err
+	}
+	chapters = dedupeChapters(cli.cfg, positional[0], chapters, dedupeMode, *groups)
+
+	if *jsonOutput {
+		return writeJSON(cli.stdout, chapterOutputs(chapters))
+	}
+
+	for _, chapter := range chapters {
+		fmt.Fprintf(cli.stdout, "%s\t%s\t%s\t%s\n", chapter.ID, manga.FormatChapterLabel(chapter), chapter.Language, chapter.Group)
+	}
+	return nil
+}
+
+func runPush(ctx context.Context, cli *cliContext, args []string) error {
+	flags := newFlagSet("push", cli.stderr)
+	jsonOutput := flags.Bool("json", false, "print JSON output")
+	title := flags.String("title", "", "folder name on the device (defaults to the manga id)")
+	chapterSpec := flags.String("chapters", "all", "chapter numbers to push, e.g. 1-10,12")
+	folder := flags.String("folder", cli.cfg.MangaFolder, "folder template on the device, e.g. Manga/{series}/Volume {volume}")
+	existingSpec := flags.String("existing", string(app.ExistingSkip), "what to do with chapters already on the device: skip, overwrite or copy")
+	languages := flags.String("languages", strings.Join(cli.cfg.Providers.ChapterLanguages, ","), "preferred chapter languages, e.g. es,en")
+	dedupeSpec := flags.String("dedupe", string(cli.cfg.Providers.Dedupe), "duplicate uploads of a chapter: all or best")
+	groups := flags.String("group", "", "preferred scanlation groups, e.g. group-a,group-b (defaults to the series setting)")
+	bundleSpec := flags.String("bundle", string(app.BundleChapter), "archive layout: chapter, volume, or a number of chapters per archive")
+	targetSpec := flags.String("target", cli.cfg.Output.Target, "where to write archives: boox, local or both")
+	localDir := flags.String("local-dir", cli.cfg.Output.LocalDir, "directory for local output")
+	formatSpec := flags.String("format", string(app.OutputCBZ), "archive format: cbz, epub or pdf")
+	transcode := flags.Bool("transcode", cli.cfg.Images.Transcode, "convert GIF pages to PNG (WebP pages fail, no decoder is available)")
+	optimize := flags.Bool("optimize", cli.cfg.Images.Optimize, "re-encode pages for e-ink using the images settings")
+	grayscale := flags.Bool("grayscale", cli.cfg.Images.Grayscale, "convert pages to grayscale when optimising")
+	quality := flags.Int("quality", cli.cfg.Images.Quality, "JPEG quality for optimised and PDF pages (1-100)")
+	spreadSpec := flags.String("spreads", "", "double-page spreads: off, split or rotate (defaults to the series setting)")
+	crop := flags.Bool("crop", false, "trim blank page margins (defaults to the series setting)")
+	positional, err := parseInterspersed(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("%w: expected exactly one manga id", errUsage)
+	}
+	mangaID := positional[0]
+
+	ranges, err := parseChapterRanges(*chapterSpec)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+
+	dedupeMode, err := manga.ParseDedupeMode(*dedupeSpec)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+
+	existingMode, err := app.ParseExistingMode(*existingSpec)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+
+	bundleMode, bundleSize, err := app.ParseBundle(*bundleSpec)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+
+	outputFormat, err := app.ParseOutputFormat(*formatSpec)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+
+	if *quality < 0 || *quality > 100 || (flagSet(flags, "quality") && *quality < 1) {
+		return fmt.Errorf("%w: --quality must be between 1 and 100", errUsage)
+	}
+
+	images := cli.cfg.ImagesForSeries(mangaID)
+	if flagSet(flags, "crop") {
+		images.Crop = *crop
+	}
+	if *spreadSpec != "" {
+		images.Spreads, err = imaging.ParseSpreadMode(*spreadSpec)
+		if err != nil {
+			return fmt.Errorf("%w: %v", errUsage, err)
+		}
+	}
+
+	target, err := app.ParseTarget(*targetSpec)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+
+	var booxClient *boox.Client
+	deviceModel := ""
+	if target.UsesBoox() {
+		client, device, err := connectBoox(ctx, cli)
+		if err != nil {
+			return err
+		}
+		booxClient, deviceModel = client, device.Model
+	}
+	sink, err := app.NewSink(target, booxClient, *localDir)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+
+	provider := newMangaDex(cli, *languages)
+	fetchCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	chapters, err := provider.FetchChapters(fetchCtx, mangaID)
+	cancel()
+	if err != nil {
+		return err
+	}
+	chapters = dedupeChapters(cli.cfg, mangaID, chapters, dedupeMode, *groups)
+
+	selected := filterChapters(chapters, ranges)
+	if len(selected) == 0 {
+		return fmt.Errorf("no chapters match %q", *chapterSpec)
+	}
+
+	mangaTitle := strings.TrimSpace(*title)
+	if mangaTitle == "" {
+		mangaTitle = mangaID
+	}
+
+	updates := make(chan app.ProgressUpdate)
+	done := make(chan struct{})
+	go func() {
+		for update := range updates {
+			if update.PagesTotal > 0 && update.PagesDone < update.PagesTotal {
+				continue
+			}
+			if update.BytesDone > 0 && update.BytesDone != update.BytesTotal {
+				continue
+			}
+			if update.Total > 0 {
+				fmt.Fprintf(cli.stderr, "[%d/%d] %s\n", update.Current, update.Total, update.Message)
+			} else {
+				fmt.Fprintln(cli.stderr, update.Message)
+			}
+		}
+		close(done)
+	}()
+
+	images.Transcode, images.Optimize, images.Grayscale, images.Quality = *transcode, *optimize, *grayscale, *quality
+	options := app.MangaUploadOptions{
+		Existing:       existingMode,
+		FolderTemplate: *folder,
+		Bundle:         bundleMode,
+		BundleSize:     bundleSize,
+		Format:         outputFormat,
+		Images:         images.ForDevice(deviceModel),
+	}
+	summary, err := app.DownloadAndUploadMangaChapters(ctx, sink, provider, mangaID, mangaTitle, selected, options, updates)
+	close(updates)
+	<-done
+
+	if *jsonOutput {
+		output := pushOutput{
+			MangaID:  mangaID,
+			Title:    mangaTitle,
+			Chapters: chapterOutputs(selected),
+			Uploaded: chapterOutputs(summary.Uploaded),
+			Existing: chapterOutputs(summary.Existing),
+			Skipped:  chapterOutputs(summary.Skipped),
+			Aborted:  chapterOutputs(summary.Aborted),
+		}
+		if err != nil {
+			output.Error = err.Error()
+		}
+		if writeErr := writeJSON(cli.stdout, output); writeErr != nil {
+			return writeErr
+		}
+	} else {
+		fmt.Fprintf(cli.stdout, "Uploaded %d chapter(s) to %s\n", len(summary.Uploaded), mangaTitle)
+		if len(summary.Existing) > 0 {
+			fmt.Fprintf(cli.stdout, "Already on device: %d chapter(s)\n", len(summary.Existing))
+		}
+		if len(summary.Skipped) > 0 {
+			fmt.Fprintf(cli.stdout, "Skipped %d chapter(s)\n", len(summary.Skipped))
+		}
+		if len(summary.Aborted) > 0 {
+			fmt.Fprintf(cli.stdout, "Not uploaded: %s\n", strings.Join(chapterLabels(summary.Aborted), ", "))
+		}
+	}
+
+	if errors.Is(err, context.Canceled) && len(summary.Uploaded) > 0 {
+		return fmt.Errorf("%w: %v", errInterrupted, err)
+	}
+	return err
+}
+
+func chapterLabels(chapters []manga.Chapter) []string {
+	labels := make([]string, 0, len(chapters))
+	for _, chapter := range chapters {
+		labels = append(labels, manga.FormatChapterLabel(chapter))
+	}
+	return labels
+}
+
+func runSync(ctx context.Context, cli *cliContext, args []string) error {
+	flags := newFlagSet("sync", cli.stderr)
+	jsonOutput := flags.Bool("json", false, "print JSON output")
+	dryRun := flags.Bool("dry-run", false, "print the plan without changing the device")
+	deleteExtra := flags.Bool("delete", false, "delete device files that are not in the local folder")
+	folder := flags.String("folder", "", "device folder to mirror into (defaults to the library root)")
+	positional, err := parseInterspersed(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("%w: expected exactly one local directory", errUsage)
+	}
+
+	client, _, err := connectBoox(ctx, cli)
+	if err != nil {
+		return err
+	}
+
+	plan, err := app.PlanSync(ctx, client, positional[0], app.SyncOptions{Folder: *folder, Delete: *deleteExtra})
+	if err != nil {
+		return err
+	}
+
+	if !*jsonOutput {
+		for _, action := range plan.Actions {
+			fmt.Fprintln(cli.stdout, action.String())
+		}
+		fmt.Fprintf(cli.stdout, "%d change(s), %d file(s) already in sync\n", len(plan.Actions), plan.Unchanged)
+	}
+
+	if !*dryRun && len(plan.Actions) > 0 {
+		updates := make(chan app.ProgressUpdate)
+		done := make(chan struct{})
+		go func() {
+			for update := range updates {
+				if update.BytesDone > 0 && update.BytesDone != update.BytesTotal {
+					continue
+				}
+				fmt.Fprintf(cli.stderr, "[%d/%d] %s\n", update.Current, update.Total, update.Message)
+			}
+			close(done)
+		}()
+		err = app.ApplySync(ctx, client, plan, updates)
+		close(updates)
+		<-done
+	}
+
+	if *jsonOutput {
+		output := syncOutput{Actions: plan.Actions, Unchanged: plan.Unchanged, Applied: !*dryRun && err == nil}
+		if output.Actions == nil {
+			output.Actions = []app.SyncAction{}
+		}
+		if err != nil {
+			output.Error = err.Error()
+		}
+		if writeErr := writeJSON(cli.stdout, output); writeErr != nil {
+			return writeErr
+		}
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return fmt.Errorf("%w: %v", errInterrupted, err)
+	}
+	return err
+}
+
+func runDevice(ctx context.Context, cli *cliContext, args []string) error {
+	flags := newFlagSet("device", cli.stderr)
+	jsonOutput := flags.Bool("json", false, "print JSON output")
+	positional, err := parseInterspersed(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		return fmt.Errorf("%w: unexpected arguments %v", errUsage, positional)
+	}
+
+	_, device, err := connectBoox(ctx, cli)
+	if err != nil {
+		return err
+	}
+
+	if *jsonOutput {
+		return writeJSON(cli.stdout, device)
+	}
+
+	fmt.Fprintf(cli.stdout, "Model:   %s\n", device.Model)
+	fmt.Fprintf(cli.stdout, "Host:    %s\n", device.Host)
+	fmt.Fprintf(cli.stdout, "ID:      %s\n", device.ID)
+	fmt.Fprintf(cli.stdout, "Storage: %s / %s\n", device.StorageUsed, device.StorageTotal)
+	return nil
+}
+
+func newMangaDex(cli *cliContext, languages string) *mangadex.Provider {
+	var codes []string
+	if strings.TrimSpace(languages) != "" {
+		codes = strings.Split(languages, ",")
+	}
+	return mangadex.New(cli.httpClient, cli.cfg.Providers.MangaDexAPIKey).WithLanguages(codes).WithContentRatings(cli.cfg.Providers.ContentRatings)
+}
+
+func connectBoox(ctx context.Context, cli *cliContext) (*boox.Client, *boox.DeviceDetails, error) {
+	baseURL, err := cli.cfg.BaseURL()
+	if err != nil {
+		return nil, nil, err
+	}
+	client := boox.NewClient(baseURL, cli.httpClient)
+
+	checkCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	device, err := client.CheckConnection(checkCtx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", errDeviceUnreachable, err)
+	}
+
+	return client, device, nil
+}
+
+func flagSet(flags *flag.FlagSet, name string) bool {
+	set := false
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+func newFlagSet(name string, output io.Writer) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(output)
+	return flags
+}
+
+func parseInterspersed(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w: %v", errUsage, err)
+		}
+		args = flags.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+func writeJSON(writer io.Writer, value interface{}) error {
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(value); err != nil {
+		return fmt.Errorf("unable to encode output: %w", err)
+	}
+	return nil
+}
+
+func dedupeChapters(cfg config.Config, mangaID string, chapters []manga.Chapter, mode manga.DedupeMode, groups string) []manga.Chapter {
+	cfg.Providers.Dedupe = mode
+	if strings.TrimSpace(groups) != "" {
+		series := cfg.Series[mangaID]
+		series.Groups = nil
+		for _, group := range strings.Split(groups, ",") {
+			if group = strings.TrimSpace(group); group != "" {
+				series.Groups = append(series.Groups, group)
+			}
+		}
+		cfg.Series = map[string]config.SeriesConfig{mangaID: series}
+	}
+	return cfg.ChaptersForSeries(mangaID, chapters)
+}
+
+func chapterOutputs(chapters []manga.Chapter) []chapterOutput {
+	output := make([]chapterOutput, 0, len(chapters))
+	for _, chapter := range chapters {
+		item := chapterOutput{
+			ID:       chapter.ID,
+			Number:   chapter.Number,
+			Title:    chapter.Title,
+			Volume:   chapter.Volume,
+			Language: chapter.Language,
+			Group:    chapter.Group,
+			Uploader: chapter.Uploader,
+			Pages:    chapter.Pages,
+			Label:    manga.FormatChapterLabel(chapter),
+		}
+		if !chapter.PublishedAt.IsZero() {
+			publishedAt := chapter.PublishedAt
+			item.PublishedAt = &publishedAt
+		}
+		output = append(output, item)
+	}
+	return output
+}
+
+type chapterRange struct {
+	start float64
+	end   float64
+}
+
+func parseChapterRanges(spec string) ([]chapterRange, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" || strings.EqualFold(spec, "all") {
+		return nil, nil
+	}
+
+	var ranges []chapterRange
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		startText, endText, isRange := strings.Cut(part, "-")
+		start, err := parseChapterBound(startText, 0)
+		if err != nil {
+			return nil, fmt.Errorf("invalid chapter range %q", part)
+		}
+		end := start
+		if isRange {
+			end, err = parseChapterBound(endText, math.Inf(1))
+			if err != nil {
+				return nil, fmt.Errorf("invalid chapter range %q", part)
+			}
+		}
+		if end < start {
+			return nil, fmt.Errorf("invalid chapter range %q: end before start", part)
+		}
+		ranges = append(ranges, chapterRange{start: start, end: end})
+	}
+
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("invalid chapter selection %q", spec)
+	}
+	return ranges, nil
+}
+
+func parseChapterBound(value string, fallback float64) (float64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return fallback, nil
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+func filterChapters(chapters []manga.Chapter, ranges []chapterRange) []manga.Chapter {
+	if len(ranges) == 0 {
+		return chapters
+	}
+
+	selected := []manga.Chapter{}
+	for _, chapter := range chapters {
+		if chapter.Number == "" {
+			continue
+		}
+		for _, bounds := range ranges {
+			if chapter.NumericChapter >= bounds.start && chapter.NumericChapter <= bounds.end {
+				selected = append(selected, chapter)
+				break
+			}
+		}
+	}
+	return selected
+}
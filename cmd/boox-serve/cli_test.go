@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/ssh-vom/boox-serve/internal/config"
+	"github.com/ssh-vom/boox-serve/internal/providers/manga"
+)
+
+func TestParseChapterRangesAll(t *testing.T) {
+	ranges, err := parseChapterRanges("all")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if ranges != nil {
+		t.Fatalf("expected nil ranges for all, got %v", ranges)
+	}
+}
+
+func TestParseChapterRangesRejectsInvalid(t *testing.T) {
+	for _, spec := range []string{"abc", "10-1", "1-x", ","} {
+		if _, err := parseChapterRanges(spec); err == nil {
+			t.Fatalf("expected error for %q", spec)
+		}
+	}
+}
+
+func TestFilterChaptersByRanges(t *testing.T) {
+	chapters := []manga.Chapter{
+		{ID: "a", Number: "1", NumericChapter: 1},
+		{ID: "b", Number: "2.5", NumericChapter: 2.5},
+		{ID: "c", Number: "4", NumericChapter: 4},
+		{ID: "d", Number: "12", NumericChapter: 12},
+		{ID: "e", Number: "", NumericChapter: 0},
+	}
+
+	ranges, err := parseChapterRanges("1-3, 12-")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	selected := filterChapters(chapters, ranges)
+	if len(selected) != 3 {
+		t.Fatalf("expected 3 chapters, got %d", len(selected))
+	}
+	if selected[0].ID != "a" || selected[1].ID != "b" || selected[2].ID != "d" {
+		t.Fatalf("unexpected selection: %+v", selected)
+	}
+}
+
+func TestDedupeChaptersTrimsPreferredGroups(t *testing.T) {
+	chapters := []manga.Chapter{
+		{ID: "1a", Number: "1", Group: "A", Pages: 20},
+		{ID: "1b", Number: "1", Group: "B", Pages: 10},
+	}
+	cfg := config.Config{}
+
+	deduped := dedupeChapters(cfg, "manga-1", chapters, manga.DedupeBest, " , B, A")
+	if len(deduped) != 1 || deduped[0].ID != "1b" {
+		t.Fatalf("expected the trimmed preferred group to win, got %+v", deduped)
+	}
+}
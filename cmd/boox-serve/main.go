@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"net/http"
@@ -8,9 +9,11 @@ import (
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ssh-vom/boox-serve/internal/app"
 	"github.com/ssh-vom/boox-serve/internal/boox"
 	"github.com/ssh-vom/boox-serve/internal/config"
 	"github.com/ssh-vom/boox-serve/internal/providers/manga/mangadex"
+	"github.com/ssh-vom/boox-serve/internal/providers/textbooks/libgen"
 	"github.com/ssh-vom/boox-serve/internal/ui"
 )
 
@@ -29,29 +32,66 @@ func main() {
 	}
 
 	httpClient := newHTTPClient()
-	deps, startupErr := buildDependencies(cfg, httpClient)
+	if flag.NArg() > 0 {
+		os.Exit(runCLI(cfg, httpClient, flag.Args()))
+	}
+
+	queue := loadQueue()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go queue.Run(ctx)
+
+	deps, startupErr := buildDependencies(cfg, httpClient, queue)
 
 	program := tea.NewProgram(ui.NewModel(cfg, deps, func(cfg config.Config) (ui.Dependencies, error) {
-		return buildDependencies(cfg, httpClient)
+		return buildDependencies(cfg, httpClient, queue)
 	}, startupErr), tea.WithAltScreen())
 
 	if _, err := program.Run(); err != nil {
+		cancel()
 		fmt.Fprintf(os.Stderr, "TUI error: %v\n", err)
 		os.Exit(1)
 	}
 }
 
-func buildDependencies(cfg config.Config, httpClient *http.Client) (ui.Dependencies, error) {
+func loadQueue() *app.Queue {
+	queuePath, err := config.QueuePath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Download queue will not be saved: %v\n", err)
+	}
+
+	queue, err := app.LoadQueue(queuePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading download queue: %v\n", err)
+	}
+	return queue
+}
+
+func buildDependencies(cfg config.Config, httpClient *http.Client, queue *app.Queue) (ui.Dependencies, error) {
 	deps := ui.Dependencies{
-		MangaProvider: mangadex.New(httpClient, cfg.Providers.MangaDexAPIKey),
+		MangaProvider:    mangadex.New(httpClient, cfg.Providers.MangaDexAPIKey).WithLanguages(cfg.Providers.ChapterLanguages).WithContentRatings(cfg.Providers.ContentRatings),
+		TextbookProvider: libgen.New(httpClient, cfg.Providers.LibGenMirror),
+		HTTPClient:       httpClient,
+		Queue:            queue,
 	}
 
-	baseURL, err := cfg.BaseURL()
+	target, err := app.ParseTarget(cfg.Output.Target)
 	if err != nil {
+		queue.Configure(nil, deps.MangaProvider)
 		return deps, err
 	}
-	deps.BooxClient = boox.NewClient(baseURL, httpClient)
-	return deps, nil
+
+	baseURL, baseErr := cfg.BaseURL()
+	if baseErr == nil {
+		deps.BooxClient = boox.NewClient(baseURL, httpClient)
+	}
+
+	deps.Sink, err = app.NewSink(target, deps.BooxClient, cfg.Output.LocalDir)
+	queue.Configure(deps.Sink, deps.MangaProvider)
+	if baseErr != nil {
+		return deps, baseErr
+	}
+	return deps, err
 }
 
 func newHTTPClient() *http.Client {
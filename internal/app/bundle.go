@@ -0,0 +1,139 @@
+package app
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ssh-vom/boox-serve/internal/providers/manga"
+)
+
+type BundleMode string
+
+const (
+	BundleChapter BundleMode = "chapter"
+	BundleVolume  BundleMode = "volume"
+	BundleBatch   BundleMode = "batch"
+)
+
+const DefaultBundleSize = 10
+
+func ParseBundle(value string) (BundleMode, int, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	switch value {
+	case "", string(BundleChapter):
+		return BundleChapter, 0, nil
+	case string(BundleVolume):
+		return BundleVolume, 0, nil
+	case string(BundleBatch):
+		return BundleBatch, DefaultBundleSize, nil
+	}
+
+	size, err := strconv.Atoi(value)
+	if err != nil || size < 1 {
+		return "", 0, fmt.Errorf("unknown bundle mode %q (expected chapter, volume or a chapter count)", value)
+	}
+	if size == 1 {
+		return BundleChapter, 0, nil
+	}
+	return BundleBatch, size, nil
+}
+
+func (options MangaUploadOptions) NextBundle() MangaUploadOptions {
+	switch options.Bundle {
+	case BundleVolume:
+		options.Bundle, options.BundleSize = BundleBatch, DefaultBundleSize
+	case BundleBatch:
+		options.Bundle, options.BundleSize = BundleChapter, 0
+	default:
+		options.Bundle, options.BundleSize = BundleVolume, 0
+	}
+	return options
+}
+
+func (options MangaUploadOptions) BundleLabel() string {
+	switch options.Bundle {
+	case BundleVolume:
+		return "per volume"
+	case BundleBatch:
+		return fmt.Sprintf("%d chapters", options.bundleSize())
+	default:
+		return "per chapter"
+	}
+}
+
+func (options MangaUploadOptions) bundleSize() int {
+	if options.BundleSize < 2 {
+		return DefaultBundleSize
+	}
+	return options.BundleSize
+}
+
+type chapterBundle struct {
+	name     string
+	chapters []manga.Chapter
+}
+
+func (bundle chapterBundle) fileName(format OutputFormat) string {
+	return sanitizeFileName(bundle.name) + format.Extension()
+}
+
+func bundleChapters(chapters []manga.Chapter, options MangaUploadOptions) []chapterBundle {
+	bundles := []chapterBundle{}
+	switch options.Bundle {
+	case BundleVolume:
+		indexes := map[string]int{}
+		for _, chapter := range chapters {
+			volume := strings.TrimSpace(chapter.Volume)
+			if volume == "" {
+				bundles = append(bundles, singleChapterBundle(chapter))
+				continue
+			}
+			if index, ok := indexes[volume]; ok {
+				bundles[index].chapters = append(bundles[index].chapters, chapter)
+				continue
+			}
+			indexes[volume] = len(bundles)
+			bundles = append(bundles, chapterBundle{name: "Volume " + volume, chapters: []manga.Chapter{chapter}})
+		}
+	case BundleBatch:
+		size := options.bundleSize()
+		for start := 0; start < len(chapters); start += size {
+			end := start + size
+			if end > len(chapters) {
+				end = len(chapters)
+			}
+			batch := chapters[start:end]
+			if len(batch) == 1 {
+				bundles = append(bundles, singleChapterBundle(batch[0]))
+				continue
+			}
+			bundles = append(bundles, chapterBundle{name: batchName(batch, start/size+1), chapters: batch})
+		}
+	default:
+		for _, chapter := range chapters {
+			bundles = append(bundles, singleChapterBundle(chapter))
+		}
+	}
+	return bundles
+}
+
+func singleChapterBundle(chapter manga.Chapter) chapterBundle {
+	return chapterBundle{name: manga.FormatChapterLabel(chapter), chapters: []manga.Chapter{chapter}}
+}
+
+func batchName(batch []manga.Chapter, part int) string {
+	first, last := batch[0].Number, batch[len(batch)-1].Number
+	if first == "" || last == "" {
+		return fmt.Sprintf("Part %d", part)
+	}
+	return fmt.Sprintf("Chapters %s-%s", first, last)
+}
+
+func chapterIDs(chapters []manga.Chapter) []string {
+	ids := make([]string, 0, len(chapters))
+	for _, chapter := range chapters {
+		ids = append(ids, chapter.ID)
+	}
+	return ids
+}
@@ -0,0 +1,99 @@
+package app
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+
+	"github.com/ssh-vom/boox-serve/internal/imaging"
+	"github.com/ssh-vom/boox-serve/internal/providers/manga"
+)
+
+func TestBundleChaptersByVolume(t *testing.T) {
+	chapters := []manga.Chapter{
+		{ID: "c1", Number: "1", Volume: "1"},
+		{ID: "c2", Number: "2", Volume: "1"},
+		{ID: "c3", Number: "3"},
+		{ID: "c4", Number: "4", Volume: "2"},
+	}
+
+	bundles := bundleChapters(chapters, MangaUploadOptions{Bundle: BundleVolume})
+	if len(bundles) != 3 {
+		t.Fatalf("expected 3 bundles, got %d", len(bundles))
+	}
+	if bundles[0].fileName(OutputCBZ) != "Volume 1.cbz" || len(bundles[0].chapters) != 2 {
+		t.Fatalf("unexpected first bundle: %+v", bundles[0])
+	}
+	if bundles[1].fileName(OutputCBZ) != "Chapter 3.cbz" {
+		t.Fatalf("expected chapter without volume to stay separate, got %+v", bundles[1])
+	}
+}
+
+func TestBundleChaptersInBatches(t *testing.T) {
+	chapters := []manga.Chapter{{ID: "c1", Number: "1"}, {ID: "c2", Number: "2"}, {ID: "c3", Number: "3"}}
+
+	bundles := bundleChapters(chapters, MangaUploadOptions{Bundle: BundleBatch, BundleSize: 2})
+	if len(bundles) != 2 {
+		t.Fatalf("expected 2 bundles, got %d", len(bundles))
+	}
+	if bundles[0].name != "Chapters 1-2" || bundles[1].name != "Chapter 3" {
+		t.Fatalf("unexpected bundle names %q and %q", bundles[0].name, bundles[1].name)
+	}
+}
+
+func TestParseBundle(t *testing.T) {
+	tests := []struct {
+		value string
+		mode  BundleMode
+		size  int
+	}{
+		{value: "", mode: BundleChapter},
+		{value: "volume", mode: BundleVolume},
+		{value: "batch", mode: BundleBatch, size: DefaultBundleSize},
+		{value: "5", mode: BundleBatch, size: 5},
+		{value: "1", mode: BundleChapter},
+	}
+
+	for _, test := range tests {
+		mode, size, err := ParseBundle(test.value)
+		if err != nil {
+			t.Fatalf("ParseBundle(%q) returned %v", test.value, err)
+		}
+		if mode != test.mode || size != test.size {
+			t.Fatalf("ParseBundle(%q) = %s/%d, want %s/%d", test.value, mode, size, test.mode, test.size)
+		}
+	}
+
+	if _, _, err := ParseBundle("0"); err == nil {
+		t.Fatalf("expected error for zero bundle size")
+	}
+}
+
+func TestCreateBundleCBZKeepsChapterDirectoriesInOrder(t *testing.T) {
+	data, err := createCBZ([]cbzChapter{
+		{name: "Chapter 1", images: []imaging.Page{{Data: []byte("a"), Format: imaging.JPEG}, {Data: []byte("b"), Format: imaging.PNG}}},
+		{name: "Chapter 2", images: []imaging.Page{{Data: []byte("c"), Format: imaging.WebP}}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("unable to read archive: %v", err)
+	}
+
+	want := []string{
+		"Chapter 1/Chapter 1_page_001.jpg",
+		"Chapter 1/Chapter 1_page_002.png",
+		"Chapter 2/Chapter 2_page_001.webp",
+	}
+	if len(reader.File) != len(want) {
+		t.Fatalf("expected %d entries, got %d", len(want), len(reader.File))
+	}
+	for index, file := range reader.File {
+		if file.Name != want[index] {
+			t.Fatalf("entry %d = %q, want %q", index, file.Name, want[index])
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package app
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/ssh-vom/boox-serve/internal/providers/manga"
+)
+
+const comicInfoFileName = "ComicInfo.xml"
+
+type comicInfo struct {
+	XMLName     xml.Name `xml:"ComicInfo"`
+	XSI         string   `xml:"xmlns:xsi,attr"`
+	XSD         string   `xml:"xmlns:xsd,attr"`
+	Title       string   `xml:"Title,omitempty"`
+	Series      string   `xml:"Series,omitempty"`
+	Number      string   `xml:"Number,omitempty"`
+	Volume      string   `xml:"Volume,omitempty"`
+	Summary     string   `xml:"Summary,omitempty"`
+	Year        int      `xml:"Year,omitempty"`
+	Writer      string   `xml:"Writer,omitempty"`
+	Penciller   string   `xml:"Penciller,omitempty"`
+	Genre       string   `xml:"Genre,omitempty"`
+	Tags        string   `xml:"Tags,omitempty"`
+	PageCount   int      `xml:"PageCount,omitempty"`
+	LanguageISO string   `xml:"LanguageISO,omitempty"`
+	Manga       string   `xml:"Manga,omitempty"`
+	AgeRating   string   `xml:"AgeRating,omitempty"`
+}
+
+func newComicInfo(series manga.Series, seriesTitle string, bundle chapterBundle, chapters []cbzChapter) comicInfo {
+	info := comicInfo{
+		XSI:       "http://www.w3.org/2001/XMLSchema-instance",
+		XSD:       "http://www.w3.org/2001/XMLSchema",
+		Series:    strings.TrimSpace(seriesTitle),
+		Summary:   series.Description,
+		Year:      series.Year,
+		Writer:    strings.Join(series.Authors, ", "),
+		Penciller: strings.Join(series.Artists, ", "),
+		Genre:     strings.Join(series.Genres, ", "),
+		Tags:      strings.Join(series.Tags, ", "),
+		Manga:     "Yes",
+		AgeRating: ageRating(series.ContentRating),
+	}
+	if info.Series == "" {
+		info.Series = series.Title
+	}
+	if series.RightToLeft() {
+		info.Manga = "YesAndRightToLeft"
+	}
+
+	for _, chapter := range chapters {
+		info.PageCount += len(chapter.images)
+	}
+
+	if len(chapters) == 1 {
+		chapter := chapters[0].chapter
+		info.Title = chapter.Title
+		info.Number = chapter.Number
+		info.Volume = chapter.Volume
+		info.LanguageISO = chapter.Language
+		return info
+	}
+
+	info.Title = bundle.name
+	info.Volume = chapters[0].chapter.Volume
+	info.LanguageISO = chapters[0].chapter.Language
+	for _, chapter := range chapters[1:] {
+		if chapter.chapter.Volume != info.Volume {
+			info.Volume = ""
+		}
+		if chapter.chapter.Language != info.LanguageISO {
+			info.LanguageISO = ""
+		}
+	}
+	return info
+}
+
+func (info comicInfo) marshal() ([]byte, error) {
+	data, err := xml.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error encoding %s: %w", comicInfoFileName, err)
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+func ageRating(contentRating string) string {
+	switch contentRating {
+	case "safe":
+		return "Everyone"
+	case "suggestive":
+		return "Teen"
+	case "erotica":
+		return "Mature 17+"
+	case "pornographic":
+		return "Adults Only 18+"
+	default:
+		return ""
+	}
+}
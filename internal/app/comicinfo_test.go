@@ -0,0 +1,64 @@
+package app
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"testing"
+
+	"github.com/ssh-vom/boox-serve/internal/imaging"
+	"github.com/ssh-vom/boox-serve/internal/providers/manga"
+)
+
+func TestCreateCBZWritesComicInfo(t *testing.T) {
+	chapter := manga.Chapter{ID: "c1", Number: "12", Title: "Arrival", Volume: "2", Language: "en"}
+	series := manga.Series{
+		Title:         "Series",
+		Description:   "A summary",
+		Authors:       []string{"Author One", "Author Two"},
+		Genres:        []string{"Action"},
+		Language:      "ja",
+		ContentRating: "safe",
+		Year:          2004,
+	}
+	pages := []cbzChapter{{chapter: chapter, name: "Chapter 12", images: []imaging.Page{{Data: []byte("a"), Format: imaging.JPEG}, {Data: []byte("b"), Format: imaging.JPEG}}}}
+
+	comicInfoData, err := newComicInfo(series, "My Series", singleChapterBundle(chapter), pages).marshal()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	data, err := createCBZ(pages, comicInfoData)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("unable to read archive: %v", err)
+	}
+	if reader.File[0].Name != comicInfoFileName {
+		t.Fatalf("expected %s first, got %s", comicInfoFileName, reader.File[0].Name)
+	}
+
+	file, err := reader.File[0].Open()
+	if err != nil {
+		t.Fatalf("unable to open %s: %v", comicInfoFileName, err)
+	}
+	raw, _ := io.ReadAll(file)
+	file.Close()
+
+	var info comicInfo
+	if err := xml.Unmarshal(raw, &info); err != nil {
+		t.Fatalf("unable to parse %s: %v", comicInfoFileName, err)
+	}
+	if info.Series != "My Series" || info.Number != "12" || info.Volume != "2" || info.Title != "Arrival" {
+		t.Fatalf("unexpected chapter fields: %+v", info)
+	}
+	if info.Writer != "Author One, Author Two" || info.Genre != "Action" || info.Summary != "A summary" {
+		t.Fatalf("unexpected series fields: %+v", info)
+	}
+	if info.PageCount != 2 || info.LanguageISO != "en" || info.Manga != "YesAndRightToLeft" || info.Year != 2004 {
+		t.Fatalf("unexpected reading fields: %+v", info)
+	}
+}
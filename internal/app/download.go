@@ -6,28 +6,62 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"path/filepath"
 	"strings"
 
 	"github.com/ssh-vom/boox-serve/internal/boox"
+	"github.com/ssh-vom/boox-serve/internal/imaging"
 	"github.com/ssh-vom/boox-serve/internal/providers/manga"
 )
 
 type TitleAndHash struct {
-	Title string
-	Hash  string
+	Title     string
+	Hash      string
+	Extension string
 }
 
 type ProgressUpdate struct {
-	Current int
-	Total   int
-	Message string
-	Done    bool
-	Err     error
+	Current          int
+	Total            int
+	PagesDone        int
+	PagesTotal       int
+	BytesDone        int64
+	BytesTotal       int64
+	UploadedChapters []string
+	Message          string
+	Done             bool
+	Summary          *DownloadSummary
+	Err              error
 }
 
+type DownloadSummary struct {
+	Uploaded []manga.Chapter
+	Existing []manga.Chapter
+	Skipped  []manga.Chapter
+	Aborted  []manga.Chapter
+}
+
+func (summary *DownloadSummary) abortRemaining(chapters []manga.Chapter) {
+	skipped := make(map[string]bool, len(summary.Skipped))
+	for _, chapter := range summary.Skipped {
+		skipped[chapter.ID] = true
+	}
+	for _, chapter := range chapters {
+		if !skipped[chapter.ID] {
+			summary.Aborted = append(summary.Aborted, chapter)
+		}
+	}
+}
+
+func (summary *DownloadSummary) abortBundles(bundles []chapterBundle) {
+	for _, bundle := range bundles {
+		summary.abortRemaining(bundle.chapters)
+	}
+}
+
+const uploadProgressStep = 256 * 1024
+
 type progressTracker struct {
 	updates chan<- ProgressUpdate
 	total   int
@@ -45,6 +79,33 @@ func (tracker *progressTracker) message(message string) {
 	tracker.updates <- ProgressUpdate{Current: tracker.current, Total: tracker.total, Message: message}
 }
 
+func (tracker *progressTracker) pages(message string, done, total int) {
+	if tracker == nil || tracker.updates == nil {
+		return
+	}
+	tracker.updates <- ProgressUpdate{Current: tracker.current, Total: tracker.total, PagesDone: done, PagesTotal: total, Message: message}
+}
+
+func (tracker *progressTracker) uploadProgress(message string) boox.UploadProgress {
+	if tracker == nil || tracker.updates == nil {
+		return nil
+	}
+
+	current, total := tracker.current, tracker.total
+	var reported int64
+	return func(sent, size int64) {
+		step := size / 100
+		if step < uploadProgressStep {
+			step = uploadProgressStep
+		}
+		if sent != size && sent-reported < step {
+			return
+		}
+		reported = sent
+		tracker.updates <- ProgressUpdate{Current: current, Total: total, BytesDone: sent, BytesTotal: size, Message: message}
+	}
+}
+
 func (tracker *progressTracker) advance(message string) {
 	if tracker == nil {
 		return
@@ -57,7 +118,15 @@ func (tracker *progressTracker) advance(message string) {
 	}
 }
 
+func (tracker *progressTracker) uploaded(message string, chapterIDs []string) {
+	tracker.complete(1, message, chapterIDs)
+}
+
 func (tracker *progressTracker) skip(steps int, message string) {
+	tracker.complete(steps, message, nil)
+}
+
+func (tracker *progressTracker) complete(steps int, message string, chapterIDs []string) {
 	if tracker == nil {
 		return
 	}
@@ -66,14 +135,26 @@ func (tracker *progressTracker) skip(steps int, message string) {
 		tracker.current = tracker.total
 	}
 	if tracker.updates != nil {
-		tracker.updates <- ProgressUpdate{Current: tracker.current, Total: tracker.total, Message: message}
+		tracker.updates <- ProgressUpdate{Current: tracker.current, Total: tracker.total, UploadedChapters: chapterIDs, Message: message}
 	}
 }
 
-func DownloadAndUploadLibGen(ctx context.Context, booxClient *boox.Client, httpClient *http.Client, items []TitleAndHash) error {
-	for _, item := range items {
+func DownloadAndUploadLibGen(ctx context.Context, sink Sink, httpClient *http.Client, items []TitleAndHash, updates chan<- ProgressUpdate) error {
+	if len(items) == 0 {
+		return fmt.Errorf("no textbooks selected")
+	}
+
+	tracker := newProgressTracker(updates, len(items))
+	httpClient = streamingClient(httpClient)
+
+	for index, item := range items {
+		if ctx.Err() != nil {
+			return fmt.Errorf("download cancelled: %w", ctx.Err())
+		}
+		prefix := fmt.Sprintf("Book %d/%d: ", index+1, len(items))
 		getURL := "https://cdn3.booksdl.org/get.php?" + item.Hash
 
+		tracker.message(prefix + "Downloading " + item.Title)
 		request, err := http.NewRequestWithContext(ctx, http.MethodGet, getURL, nil)
 		if err != nil {
 			return fmt.Errorf("error building download request: %w", err)
@@ -84,101 +165,339 @@ func DownloadAndUploadLibGen(ctx context.Context, booxClient *boox.Client, httpC
 			return fmt.Errorf("error downloading file: %w", err)
 		}
 
-		buffer := &bytes.Buffer{}
-		if _, err := io.Copy(buffer, response.Body); err != nil {
+		if response.StatusCode != http.StatusOK {
 			response.Body.Close()
-			return fmt.Errorf("error reading file: %w", err)
+			return fmt.Errorf("download of %s failed: %s", item.Title, response.Status)
 		}
-		response.Body.Close()
 
-		fileName := fmt.Sprintf("%s.pdf", sanitizeFileName(item.Title))
-		if err := booxClient.UploadFile(ctx, "", fileName, buffer.Bytes()); err != nil {
+		fileName := fmt.Sprintf("%s.%s", sanitizeFileName(item.Title), textbookExtension(item.Extension))
+		progress := tracker.uploadProgress(prefix + "Transferring " + item.Title)
+		err = sink.UploadReader(ctx, "", fileName, response.Body, response.ContentLength, progress)
+		response.Body.Close()
+		if err != nil {
 			return err
 		}
+		tracker.advance(prefix + "Uploaded " + item.Title)
 	}
 
 	return nil
 }
 
-func DownloadAndUploadMangaChapters(ctx context.Context, booxClient *boox.Client, provider manga.Provider, mangaTitle string, chapters []manga.Chapter, updates chan<- ProgressUpdate) error {
+func streamingClient(client *http.Client) *http.Client {
+	streaming := *client
+	if client.Timeout <= 0 {
+		return &streaming
+	}
+
+	transport, ok := client.Transport.(*http.Transport)
+	if client.Transport == nil {
+		transport, ok = http.DefaultTransport.(*http.Transport)
+	}
+	if ok {
+		transport = transport.Clone()
+		if transport.ResponseHeaderTimeout == 0 {
+			transport.ResponseHeaderTimeout = client.Timeout
+		}
+		streaming.Transport = transport
+	}
+	streaming.Timeout = 0
+	return &streaming
+}
+
+func DownloadAndUploadMangaChapters(ctx context.Context, sink Sink, provider manga.Provider, mangaID, mangaTitle string, chapters []manga.Chapter, options MangaUploadOptions, updates chan<- ProgressUpdate) (DownloadSummary, error) {
+	summary := DownloadSummary{}
 	if len(chapters) == 0 {
-		return fmt.Errorf("no chapters selected")
+		return summary, fmt.Errorf("no chapters selected")
 	}
 
-	folderName := sanitizeFileName(mangaTitle)
-	folderID, err := booxClient.CreateFolder(ctx, nil, folderName)
-	if err != nil {
-		if updates != nil {
-			updates <- ProgressUpdate{Message: "Unable to create folder, uploading to root"}
+	series := manga.Series{ID: mangaID, Title: mangaTitle}
+	if mangaID != "" {
+		fetched, err := provider.FetchSeries(ctx, mangaID)
+		if err != nil {
+			if ctx.Err() != nil {
+				summary.abortRemaining(chapters)
+				return summary, fmt.Errorf("download cancelled: %w", ctx.Err())
+			}
+			if updates != nil {
+				updates <- ProgressUpdate{Message: "Unable to fetch series metadata, writing chapter details only"}
+			}
+		} else {
+			series = fetched
 		}
-		folderID = ""
+	}
+	options.Images.RightToLeft = series.RightToLeft()
+
+	var cover *imaging.Page
+	if options.Format == OutputEPUB {
+		cover = fetchCover(ctx, provider, options, series, updates)
+	}
+
+	total := len(chapters)
+	var onDevice []manga.Chapter
+	if options.Bundle == BundleBatch && options.Existing != ExistingOverwrite && options.Existing != ExistingCopy {
+		present, err := ExistingChapters(ctx, sink, mangaTitle, chapters, options)
+		if err != nil && ctx.Err() != nil {
+			summary.abortRemaining(chapters)
+			return summary, fmt.Errorf("download cancelled: %w", ctx.Err())
+		}
+		remaining := []manga.Chapter{}
+		for _, chapter := range chapters {
+			if present[chapter.ID] {
+				onDevice = append(onDevice, chapter)
+			} else {
+				remaining = append(remaining, chapter)
+			}
+		}
+		chapters = remaining
+	}
+
+	resolver := newFolderResolver(sink, true)
+	bundles := bundleChapters(chapters, options)
+
+	const stepsPerBundle = 2
+	tracker := newProgressTracker(updates, total+len(bundles)*stepsPerBundle)
+	if len(onDevice) > 0 {
+		summary.Existing = append(summary.Existing, onDevice...)
+		tracker.complete(len(onDevice), fmt.Sprintf("%d chapter(s) already on device", len(onDevice)), chapterIDs(onDevice))
 	}
 
-	const stepsPerChapter = 3
-	tracker := newProgressTracker(updates, len(chapters)*stepsPerChapter)
+	unit := "Chapter"
+	if options.Bundle == BundleVolume || options.Bundle == BundleBatch {
+		unit = "Bundle"
+	}
 
 	var chapterErrors []error
 
-	for index, chapter := range chapters {
-		label := manga.FormatChapterLabel(chapter)
-		prefix := fmt.Sprintf("Chapter %d/%d: ", index+1, len(chapters))
+	for index, bundle := range bundles {
+		if ctx.Err() != nil {
+			summary.abortBundles(bundles[index:])
+			return summary, fmt.Errorf("download cancelled: %w", ctx.Err())
+		}
+
+		prefix := fmt.Sprintf("%s %d/%d: ", unit, index+1, len(bundles))
+
+		folderPath := FolderPath(options.FolderTemplate, mangaTitle, bundle.chapters[0])
+		folderID, err := resolver.folder(ctx, folderPath)
+		if err != nil {
+			summary.abortBundles(bundles[index:])
+			return summary, fmt.Errorf("error resolving folder %s: %w", strings.Join(folderPath, "/"), err)
+		}
 
-		tracker.message(prefix + "Downloading pages for " + label)
-		images, err := provider.DownloadChapterImages(ctx, chapter)
+		existing, err := resolver.existing(ctx, folderID)
 		if err != nil {
-			if shouldSkipChapter(err) {
-				chapterErrors = append(chapterErrors, err)
-				tracker.skip(stepsPerChapter, prefix+"Skipped "+label)
+			if ctx.Err() != nil {
+				summary.abortBundles(bundles[index:])
+				return summary, fmt.Errorf("download cancelled: %w", ctx.Err())
+			}
+			tracker.message(prefix + "Unable to read device library, uploading " + bundle.name + " anyway")
+			existing = deviceFiles{}
+			resolver.files[folderID] = existing
+		}
+
+		fileName := bundle.fileName(options.Format)
+		replaceID := ""
+		if existingID, ok := existing.lookup(fileName); ok {
+			switch options.Existing {
+			case ExistingOverwrite:
+				replaceID = existingID
+			case ExistingCopy:
+				fileName = existing.copyName(fileName)
+			default:
+				summary.Existing = append(summary.Existing, bundle.chapters...)
+				tracker.complete(len(bundle.chapters)+stepsPerBundle, prefix+bundle.name+" already on device", chapterIDs(bundle.chapters))
+				continue
+			}
+		}
+
+		included := []manga.Chapter{}
+		pages := []cbzChapter{}
+		for _, chapter := range bundle.chapters {
+			label := manga.FormatChapterLabel(chapter)
+			tracker.message(prefix + "Downloading pages for " + label)
+			images, err := provider.DownloadChapterImages(ctx, chapter, func(done, total int) {
+				tracker.pages(prefix+"Downloading pages for "+label, done, total)
+			})
+			if err != nil {
+				if shouldSkipChapter(err) {
+					chapterErrors = append(chapterErrors, err)
+					summary.Skipped = append(summary.Skipped, chapter)
+					tracker.advance(prefix + "Skipped " + label)
+					continue
+				}
+				summary.abortBundles(bundles[index:])
+				return summary, fmt.Errorf("error downloading chapter images: %w", err)
+			}
+			prepared, err := preparePages(images, options.Images)
+			if err != nil {
+				chapterErrors = append(chapterErrors, fmt.Errorf("%s: %w", label, err))
+				summary.Skipped = append(summary.Skipped, chapter)
+				tracker.advance(prefix + "Skipped " + label + " (invalid page)")
 				continue
 			}
-			return fmt.Errorf("error downloading chapter images: %w", err)
+			tracker.advance(prefix + "Downloaded pages for " + label)
+			included = append(included, chapter)
+			pages = append(pages, cbzChapter{chapter: chapter, name: sanitizeFileName(label), images: prepared})
+		}
+		if len(included) == 0 {
+			tracker.skip(stepsPerBundle, prefix+"Skipped "+bundle.name)
+			continue
 		}
-		tracker.advance(prefix + "Downloaded pages for " + label)
 
-		tracker.message(prefix + "Creating CBZ for " + label)
-		chapterName := sanitizeFileName(label)
-		cbzData, err := createCBZ(chapterName, images)
+		formatLabel := options.Format.Label()
+		tracker.message(prefix + "Creating " + formatLabel + " for " + bundle.name)
+		archiveData, err := packageBundle(options, series, mangaTitle, bundle, pages, cover)
 		if err != nil {
-			return fmt.Errorf("error creating CBZ file: %w", err)
+			summary.abortBundles(bundles[index:])
+			return summary, fmt.Errorf("error creating %s file: %w", formatLabel, err)
 		}
-		tracker.advance(prefix + "Created CBZ for " + label)
+		tracker.advance(prefix + "Created " + formatLabel + " for " + bundle.name)
 
-		tracker.message(prefix + "Uploading " + label)
-		fileName := fmt.Sprintf("%s.cbz", chapterName)
-		if err := booxClient.UploadFile(ctx, folderID, fileName, cbzData); err != nil {
-			return fmt.Errorf("error uploading CBZ file: %w", err)
+		tracker.message(prefix + "Uploading " + bundle.name)
+		progress := tracker.uploadProgress(prefix + "Uploading " + bundle.name)
+		if err := replaceReader(ctx, sink, folderID, fileName, replaceID, bytes.NewReader(archiveData), int64(len(archiveData)), progress); err != nil {
+			summary.abortBundles(bundles[index:])
+			return summary, fmt.Errorf("error uploading %s file: %w", formatLabel, err)
 		}
-		tracker.advance(prefix + "Uploaded " + label)
+		existing.add(fileName, "")
+		summary.Uploaded = append(summary.Uploaded, included...)
+		tracker.uploaded(prefix+"Uploaded "+bundle.name, chapterIDs(included))
 	}
 
 	if len(chapterErrors) > 0 {
-		return fmt.Errorf("skipped %d chapter(s): %w", len(chapterErrors), errors.Join(chapterErrors...))
+		return summary, fmt.Errorf("skipped %d chapter(s): %w", len(chapterErrors), errors.Join(chapterErrors...))
+	}
+
+	return summary, nil
+}
+
+func fetchCover(ctx context.Context, provider manga.Provider, options MangaUploadOptions, series manga.Series, updates chan<- ProgressUpdate) *imaging.Page {
+	coverURL := options.CoverURL
+	if coverURL == "" {
+		coverURL = series.CoverURL
+	}
+	if coverURL == "" {
+		return nil
 	}
 
+	data, err := provider.FetchCover(ctx, coverURL)
+	if err == nil {
+		_, err = imaging.Validate(data)
+	}
+	if err == nil {
+		coverOptions := options.Images
+		coverOptions.Crop, coverOptions.Spreads = false, imaging.SpreadsOff
+		var pages []imaging.Page
+		if pages, err = imaging.Prepare(data, coverOptions); err == nil {
+			return &pages[0]
+		}
+	}
+	if updates != nil && ctx.Err() == nil {
+		updates <- ProgressUpdate{Message: "Unable to fetch cover, using the first page instead"}
+	}
 	return nil
 }
 
+func packageBundle(options MangaUploadOptions, series manga.Series, seriesTitle string, bundle chapterBundle, pages []cbzChapter, cover *imaging.Page) ([]byte, error) {
+	info := newComicInfo(series, seriesTitle, bundle, pages)
+	switch options.Format {
+	case OutputPDF:
+		return createPDF(pdfBook{
+			title:       bundle.name,
+			series:      info.Series,
+			authors:     creators(series),
+			rightToLeft: series.RightToLeft(),
+			quality:     options.Images.JPEGQuality(),
+			chapters:    pages,
+		})
+	case OutputEPUB:
+		return createEPUB(epubBook{
+			identifier:  epubIdentifier(pages),
+			title:       bundle.name,
+			series:      info.Series,
+			authors:     creators(series),
+			description: info.Summary,
+			language:    info.LanguageISO,
+			rightToLeft: series.RightToLeft(),
+			cover:       cover,
+			chapters:    pages,
+		})
+	}
+
+	comicInfoData, err := info.marshal()
+	if err != nil {
+		return nil, err
+	}
+	return createCBZ(pages, comicInfoData)
+}
+
+func creators(series manga.Series) []string {
+	names := []string{}
+	seen := map[string]bool{}
+	for _, name := range append(append([]string{}, series.Authors...), series.Artists...) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
 func shouldSkipChapter(err error) bool {
-	return errors.Is(err, manga.ErrChapterMetadataMissing) || errors.Is(err, manga.ErrChapterNoPages)
+	return errors.Is(err, manga.ErrChapterMetadataMissing) || errors.Is(err, manga.ErrChapterNoPages) || errors.Is(err, imaging.ErrCorruptImage)
 }
 
-func createCBZ(chapterName string, images [][]byte) ([]byte, error) {
-	buf := new(bytes.Buffer)
-	zipWriter := zip.NewWriter(buf)
+type cbzChapter struct {
+	chapter manga.Chapter
+	name    string
+	images  []imaging.Page
+}
 
-	for i, imgData := range images {
-		fileName := fmt.Sprintf("%s_page_%03d.jpg", chapterName, i+1)
-		writer, err := zipWriter.Create(fileName)
+func preparePages(images [][]byte, options imaging.Options) ([]imaging.Page, error) {
+	pages := make([]imaging.Page, 0, len(images))
+	for index, data := range images {
+		prepared, err := imaging.Prepare(data, options)
 		if err != nil {
-			return nil, fmt.Errorf("error creating zip entry %s: %w", fileName, err)
+			return nil, fmt.Errorf("page %d: %w", index+1, err)
 		}
+		pages = append(pages, prepared...)
+	}
+	return pages, nil
+}
 
-		n, err := writer.Write(imgData)
+func createCBZ(chapters []cbzChapter, comicInfo []byte) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	zipWriter := zip.NewWriter(buf)
+
+	if len(comicInfo) > 0 {
+		writer, err := zipWriter.Create(comicInfoFileName)
 		if err != nil {
-			return nil, fmt.Errorf("error writing image data for %s: %w", fileName, err)
+			return nil, fmt.Errorf("error creating zip entry %s: %w", comicInfoFileName, err)
 		}
-		if n != len(imgData) {
-			return nil, fmt.Errorf("incomplete write for %s: wrote %d of %d bytes", fileName, n, len(imgData))
+		if _, err := writer.Write(comicInfo); err != nil {
+			return nil, fmt.Errorf("error writing %s: %w", comicInfoFileName, err)
+		}
+	}
+
+	subdirectories := len(chapters) > 1
+	for _, chapter := range chapters {
+		for i, page := range chapter.images {
+			imgData := page.Data
+			fileName := fmt.Sprintf("%s_page_%03d.%s", chapter.name, i+1, page.Format.Extension())
+			if subdirectories {
+				fileName = chapter.name + "/" + fileName
+			}
+			writer, err := zipWriter.Create(fileName)
+			if err != nil {
+				return nil, fmt.Errorf("error creating zip entry %s: %w", fileName, err)
+			}
+
+			n, err := writer.Write(imgData)
+			if err != nil {
+				return nil, fmt.Errorf("error writing image data for %s: %w", fileName, err)
+			}
+			if n != len(imgData) {
+				return nil, fmt.Errorf("incomplete write for %s: wrote %d of %d bytes", fileName, n, len(imgData))
+			}
 		}
 	}
 
@@ -194,6 +513,14 @@ func createCBZ(chapterName string, images [][]byte) ([]byte, error) {
 	return cbzData, nil
 }
 
+func textbookExtension(extension string) string {
+	extension = strings.ToLower(strings.Trim(strings.TrimSpace(extension), "."))
+	if extension == "" {
+		return "pdf"
+	}
+	return extension
+}
+
 func sanitizeFileName(name string) string {
 	trimmed := strings.TrimSpace(name)
 	if trimmed == "" {
@@ -0,0 +1,282 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ssh-vom/boox-serve/internal/boox"
+	"github.com/ssh-vom/boox-serve/internal/imaging"
+	"github.com/ssh-vom/boox-serve/internal/providers/manga"
+)
+
+type cancellingProvider struct {
+	fakeProvider
+	cancel context.CancelFunc
+	after  string
+}
+
+func (provider cancellingProvider) DownloadChapterImages(ctx context.Context, chapter manga.Chapter, progress manga.PageProgress) ([][]byte, error) {
+	if chapter.ID == provider.after {
+		provider.cancel()
+	}
+	return provider.fakeProvider.DownloadChapterImages(ctx, chapter, progress)
+}
+
+func TestDownloadMangaChaptersReportsCancelledChapters(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	provider := cancellingProvider{cancel: cancel, after: "c2"}
+	chapters := []manga.Chapter{{ID: "c1", Number: "1"}, {ID: "c2", Number: "2"}, {ID: "c3", Number: "3"}, {ID: "c4", Number: "4"}}
+
+	summary, err := DownloadAndUploadMangaChapters(ctx, newFakeBoox(t), provider, "", "Series", chapters, MangaUploadOptions{}, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancellation error, got %v", err)
+	}
+	if len(summary.Uploaded) != 1 || summary.Uploaded[0].ID != "c1" {
+		t.Fatalf("unexpected uploaded chapters: %+v", summary.Uploaded)
+	}
+	if len(summary.Aborted) != 3 || summary.Aborted[0].ID != "c2" {
+		t.Fatalf("unexpected aborted chapters: %+v", summary.Aborted)
+	}
+}
+
+type fakeDevice struct {
+	mu          sync.Mutex
+	uploads     []string
+	deleted     []string
+	created     int
+	existing    []boox.LibraryBook
+	failUploads bool
+}
+
+func newFakeDevice(t *testing.T, existing ...boox.LibraryBook) (*fakeDevice, *boox.Client) {
+	t.Helper()
+	device := &fakeDevice{existing: existing}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		device.mu.Lock()
+		defer device.mu.Unlock()
+
+		switch {
+		case r.URL.Path == "/api/library" && r.Method == http.MethodPost:
+			device.created++
+			json.NewEncoder(w).Encode(boox.FolderCreationResponse{ID: "new-folder"})
+		case r.URL.Path == "/api/library":
+			var args struct {
+				LibraryUniqueID string `json:"libraryUniqueId"`
+			}
+			json.Unmarshal([]byte(r.URL.Query().Get("args")), &args)
+			response := boox.LibraryResponse{}
+			if args.LibraryUniqueID == "" {
+				response.VisibleLibraryList = []boox.Library{{IDString: "series", Name: "Series"}}
+				response.LibraryCount = 1
+			} else if args.LibraryUniqueID == "series" {
+				response.VisibleBookList = device.existing
+				response.BookCount = len(device.existing)
+			}
+			json.NewEncoder(w).Encode(response)
+		case r.URL.Path == "/api/library/delete":
+			var payload struct {
+				IDString string `json:"idString"`
+			}
+			json.NewDecoder(r.Body).Decode(&payload)
+			device.deleted = append(device.deleted, payload.IDString)
+			for index, book := range device.existing {
+				if book.IDString == payload.IDString {
+					device.existing = append(device.existing[:index:index], device.existing[index+1:]...)
+					break
+				}
+			}
+		case r.URL.Path == "/api/library/upload":
+			if device.failUploads {
+				http.Error(w, "storage full", http.StatusInsufficientStorage)
+				return
+			}
+			reader, err := r.MultipartReader()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			for {
+				part, err := reader.NextPart()
+				if err != nil {
+					break
+				}
+				if part.FormName() == "file" {
+					device.uploads = append(device.uploads, part.FileName())
+					device.existing = append(device.existing, boox.LibraryBook{IDString: fmt.Sprintf("upload-%d", len(device.uploads)), Name: part.FileName()})
+				}
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return device, boox.NewClient(server.URL, server.Client())
+}
+
+func TestDownloadMangaChaptersHandlesExistingChapters(t *testing.T) {
+	chapters := []manga.Chapter{{ID: "c1", Number: "1"}, {ID: "c2", Number: "2"}}
+	existing := boox.LibraryBook{IDString: "book-1", Name: "Chapter 1.cbz"}
+
+	tests := []struct {
+		mode     ExistingMode
+		uploads  []string
+		deleted  []string
+		existing int
+	}{
+		{mode: ExistingSkip, uploads: []string{"Chapter 2.cbz"}, existing: 1},
+		{mode: ExistingOverwrite, uploads: []string{"Chapter 1.cbz", "Chapter 2.cbz"}, deleted: []string{"book-1"}},
+		{mode: ExistingCopy, uploads: []string{"Chapter 1 (2).cbz", "Chapter 2.cbz"}},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.mode), func(t *testing.T) {
+			device, client := newFakeDevice(t, existing)
+			summary, err := DownloadAndUploadMangaChapters(context.Background(), client, fakeProvider{}, "", "Series", chapters, MangaUploadOptions{Existing: test.mode}, nil)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if device.created != 0 {
+				t.Fatalf("expected existing series folder to be reused")
+			}
+			if strings.Join(device.uploads, "|") != strings.Join(test.uploads, "|") {
+				t.Fatalf("unexpected uploads: %v", device.uploads)
+			}
+			if strings.Join(device.deleted, "|") != strings.Join(test.deleted, "|") {
+				t.Fatalf("unexpected deletes: %v", device.deleted)
+			}
+			if len(summary.Existing) != test.existing {
+				t.Fatalf("unexpected existing chapters: %+v", summary.Existing)
+			}
+		})
+	}
+}
+
+type corruptPageProvider struct {
+	fakeProvider
+	corrupt string
+}
+
+func (provider corruptPageProvider) DownloadChapterImages(ctx context.Context, chapter manga.Chapter, progress manga.PageProgress) ([][]byte, error) {
+	if chapter.ID == provider.corrupt {
+		page := testImage()
+		_, err := imaging.Validate(page[:len(page)/2])
+		return nil, fmt.Errorf("downloaded image 1.png is invalid: %w", err)
+	}
+	return provider.fakeProvider.DownloadChapterImages(ctx, chapter, progress)
+}
+
+func TestDownloadMangaChaptersSkipsCorruptPages(t *testing.T) {
+	chapters := []manga.Chapter{{ID: "c1", Number: "1"}, {ID: "c2", Number: "2"}}
+	device, client := newFakeDevice(t)
+
+	summary, err := DownloadAndUploadMangaChapters(context.Background(), client, corruptPageProvider{corrupt: "c1"}, "", "Series", chapters, MangaUploadOptions{}, nil)
+	if !errors.Is(err, imaging.ErrCorruptImage) {
+		t.Fatalf("expected corrupt image error, got %v", err)
+	}
+	if len(summary.Skipped) != 1 || summary.Skipped[0].ID != "c1" {
+		t.Fatalf("unexpected skipped chapters: %+v", summary.Skipped)
+	}
+	if strings.Join(device.uploads, "|") != "Chapter 2.cbz" {
+		t.Fatalf("unexpected uploads: %v", device.uploads)
+	}
+}
+
+func TestDownloadMangaChaptersKeepsExistingChapterWhenOverwriteFails(t *testing.T) {
+	device, client := newFakeDevice(t, boox.LibraryBook{IDString: "book-1", Name: "Chapter 1.cbz"})
+	device.failUploads = true
+
+	chapters := []manga.Chapter{{ID: "c1", Number: "1"}}
+	if _, err := DownloadAndUploadMangaChapters(context.Background(), client, fakeProvider{}, "", "Series", chapters, MangaUploadOptions{Existing: ExistingOverwrite}, nil); err == nil {
+		t.Fatalf("expected upload error")
+	}
+	if len(device.deleted) != 0 {
+		t.Fatalf("expected the existing chapter to be kept, deleted %v", device.deleted)
+	}
+}
+
+func TestExistingChaptersChecksBatchesPerChapter(t *testing.T) {
+	device, client := newFakeDevice(t,
+		boox.LibraryBook{IDString: "book-1", Name: "Chapter 3.cbz"},
+		boox.LibraryBook{IDString: "book-2", Name: "Chapters 5-6 (2).cbz"},
+	)
+
+	chapters := []manga.Chapter{}
+	for number := 1; number <= 7; number++ {
+		chapters = append(chapters, manga.Chapter{ID: fmt.Sprintf("c%d", number), Number: fmt.Sprint(number), NumericChapter: float64(number)})
+	}
+
+	present, err := ExistingChapters(context.Background(), client, "Series", chapters, MangaUploadOptions{Bundle: BundleBatch, BundleSize: 3})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	got := []string{}
+	for _, chapter := range chapters {
+		if present[chapter.ID] {
+			got = append(got, chapter.ID)
+		}
+	}
+	if strings.Join(got, ",") != "c3,c5,c6" {
+		t.Fatalf("unexpected chapters on device: %v", got)
+	}
+
+	summary, err := DownloadAndUploadMangaChapters(context.Background(), client, fakeProvider{}, "", "Series", chapters, MangaUploadOptions{Bundle: BundleBatch, BundleSize: 3}, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if strings.Join(device.uploads, "|") != "Chapters 1-4.cbz|Chapter 7.cbz" {
+		t.Fatalf("expected chapters marked present not to be uploaded again, got %v", device.uploads)
+	}
+	if len(summary.Existing) != 3 || len(summary.Uploaded) != 4 {
+		t.Fatalf("unexpected summary: existing %+v, uploaded %+v", summary.Existing, summary.Uploaded)
+	}
+}
+
+func TestDownloadAndUploadLibGenStreamsPastClientTimeout(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "5")
+		for _, chunk := range []string{"bo", "o", "k!"} {
+			w.Write([]byte(chunk))
+			w.(http.Flusher).Flush()
+			time.Sleep(60 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+
+	var uploaded, fileName string
+	device := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			t.Errorf("unable to read upload: %v", err)
+			return
+		}
+		defer file.Close()
+		data, _ := io.ReadAll(file)
+		uploaded, fileName = string(data), header.Filename
+	}))
+	defer device.Close()
+
+	transport := server.Client().Transport.(*http.Transport).Clone()
+	transport.TLSClientConfig.InsecureSkipVerify = true
+	transport.DialContext = func(ctx context.Context, network, _ string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+	}
+	client := &http.Client{Transport: transport, Timeout: 100 * time.Millisecond}
+
+	items := []TitleAndHash{{Title: "Book", Hash: "md5=abc", Extension: "pdf"}}
+	if err := DownloadAndUploadLibGen(context.Background(), boox.NewClient(device.URL, device.Client()), client, items, nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if fileName != "Book.pdf" || uploaded != "book!" {
+		t.Fatalf("uploaded %q = %q", fileName, uploaded)
+	}
+}
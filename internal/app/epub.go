@@ -0,0 +1,294 @@
+package app
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha1"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/ssh-vom/boox-serve/internal/imaging"
+)
+
+const (
+	epubMimeType      = "application/epub+zip"
+	epubDefaultWidth  = 1200
+	epubDefaultHeight = 1600
+)
+
+type epubBook struct {
+	identifier  string
+	title       string
+	series      string
+	authors     []string
+	description string
+	language    string
+	rightToLeft bool
+	cover       *imaging.Page
+	chapters    []cbzChapter
+}
+
+type epubPage struct {
+	ID        string
+	Href      string
+	ImageID   string
+	ImageHref string
+	MediaType string
+	Width     int
+	Height    int
+	Title     string
+}
+
+type epubDocument struct {
+	name     string
+	template string
+	data     any
+}
+
+type epubTOCEntry struct {
+	Title string
+	Href  string
+}
+
+type epubPackage struct {
+	Identifier  string
+	Title       string
+	Series      string
+	Authors     []string
+	Description string
+	Language    string
+	Modified    string
+	Direction   string
+	Cover       *epubPage
+	CoverImage  string
+	Pages       []epubPage
+	TOC         []epubTOCEntry
+}
+
+var epubTemplates = template.Must(template.New("epub").Funcs(template.FuncMap{
+	"xml": xmlText,
+}).Parse(`
+{{define "container"}}<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+{{end}}
+{{define "opf"}}<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="book-id" xml:lang="{{xml .Language}}">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:identifier id="book-id">{{xml .Identifier}}</dc:identifier>
+    <dc:title>{{xml .Title}}</dc:title>
+    <dc:language>{{xml .Language}}</dc:language>
+{{- range .Authors}}
+    <dc:creator>{{xml .}}</dc:creator>
+{{- end}}
+{{- if .Description}}
+    <dc:description>{{xml .Description}}</dc:description>
+{{- end}}
+{{- if .Series}}
+    <meta property="belongs-to-collection" id="series">{{xml .Series}}</meta>
+    <meta refines="#series" property="collection-type">series</meta>
+{{- end}}
+    <meta property="dcterms:modified">{{.Modified}}</meta>
+    <meta property="rendition:layout">pre-paginated</meta>
+    <meta property="rendition:orientation">auto</meta>
+    <meta property="rendition:spread">none</meta>
+    <meta name="cover" content="{{.CoverImage}}"/>
+  </metadata>
+  <manifest>
+    <item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+{{- with .Cover}}
+    <item id="{{.ID}}" href="{{.Href}}" media-type="application/xhtml+xml"/>
+    <item id="{{.ImageID}}" href="{{.ImageHref}}" media-type="{{.MediaType}}" properties="cover-image"/>
+{{- end}}
+{{- range $index, $page := .Pages}}
+    <item id="{{$page.ID}}" href="{{$page.Href}}" media-type="application/xhtml+xml"/>
+    <item id="{{$page.ImageID}}" href="{{$page.ImageHref}}" media-type="{{$page.MediaType}}"{{if and (not $.Cover) (eq $index 0)}} properties="cover-image"{{end}}/>
+{{- end}}
+  </manifest>
+  <spine page-progression-direction="{{.Direction}}">
+{{- with .Cover}}
+    <itemref idref="{{.ID}}"/>
+{{- end}}
+{{- range .Pages}}
+    <itemref idref="{{.ID}}"/>
+{{- end}}
+  </spine>
+</package>
+{{end}}
+{{define "nav"}}<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" xml:lang="{{xml .Language}}">
+<head>
+  <title>{{xml .Title}}</title>
+</head>
+<body>
+  <nav epub:type="toc" id="toc">
+    <h1>{{xml .Title}}</h1>
+    <ol>
+{{- range .TOC}}
+      <li><a href="{{.Href}}">{{xml .Title}}</a></li>
+{{- end}}
+    </ol>
+  </nav>
+</body>
+</html>
+{{end}}
+{{define "page"}}<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops">
+<head>
+  <title>{{xml .Title}}</title>
+  <meta name="viewport" content="width={{.Width}}, height={{.Height}}"/>
+  <style>html, body { margin: 0; padding: 0; } img { display: block; width: {{.Width}}px; height: {{.Height}}px; }</style>
+</head>
+<body>
+  <img src="{{.ImageHref}}" alt="{{xml .Title}}"/>
+</body>
+</html>
+{{end}}
+`))
+
+func createEPUB(book epubBook) ([]byte, error) {
+	pkg := epubPackage{
+		Identifier:  book.identifier,
+		Title:       book.title,
+		Series:      book.series,
+		Authors:     book.authors,
+		Description: book.description,
+		Language:    book.language,
+		Modified:    time.Now().UTC().Format("2006-01-02T15:04:05Z"),
+		Direction:   "ltr",
+	}
+	if pkg.Language == "" {
+		pkg.Language = "und"
+	}
+	if book.rightToLeft {
+		pkg.Direction = "rtl"
+	}
+
+	images := map[string][]byte{}
+	if book.cover != nil {
+		cover := newEPUBPage("cover", "Cover", *book.cover)
+		pkg.Cover = &cover
+		pkg.CoverImage = cover.ImageID
+		images[cover.ImageHref] = book.cover.Data
+	}
+
+	number := 0
+	for _, chapter := range book.chapters {
+		for index, page := range chapter.images {
+			number++
+			entry := newEPUBPage(fmt.Sprintf("page-%04d", number), fmt.Sprintf("%s, page %d", chapter.name, index+1), page)
+			if index == 0 {
+				pkg.TOC = append(pkg.TOC, epubTOCEntry{Title: chapter.name, Href: entry.Href})
+			}
+			pkg.Pages = append(pkg.Pages, entry)
+			images[entry.ImageHref] = page.Data
+		}
+	}
+	if len(pkg.Pages) == 0 {
+		return nil, fmt.Errorf("EPUB has no pages")
+	}
+	if pkg.CoverImage == "" {
+		pkg.CoverImage = pkg.Pages[0].ImageID
+	}
+
+	buf := new(bytes.Buffer)
+	zipWriter := zip.NewWriter(buf)
+
+	mimeWriter, err := zipWriter.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return nil, fmt.Errorf("error creating zip entry mimetype: %w", err)
+	}
+	if _, err := mimeWriter.Write([]byte(epubMimeType)); err != nil {
+		return nil, fmt.Errorf("error writing mimetype: %w", err)
+	}
+
+	documents := []epubDocument{
+		{name: "META-INF/container.xml", template: "container"},
+		{name: "OEBPS/content.opf", template: "opf", data: pkg},
+		{name: "OEBPS/nav.xhtml", template: "nav", data: pkg},
+	}
+	if pkg.Cover != nil {
+		documents = append(documents, epubDocument{name: "OEBPS/" + pkg.Cover.Href, template: "page", data: pageDocument(*pkg.Cover)})
+	}
+	for _, page := range pkg.Pages {
+		documents = append(documents, epubDocument{name: "OEBPS/" + page.Href, template: "page", data: pageDocument(page)})
+	}
+
+	for _, document := range documents {
+		writer, err := zipWriter.Create(document.name)
+		if err != nil {
+			return nil, fmt.Errorf("error creating zip entry %s: %w", document.name, err)
+		}
+		if err := epubTemplates.ExecuteTemplate(writer, document.template, document.data); err != nil {
+			return nil, fmt.Errorf("error writing %s: %w", document.name, err)
+		}
+	}
+
+	imageEntries := []epubPage{}
+	if pkg.Cover != nil {
+		imageEntries = append(imageEntries, *pkg.Cover)
+	}
+	imageEntries = append(imageEntries, pkg.Pages...)
+	for _, entry := range imageEntries {
+		name := "OEBPS/" + entry.ImageHref
+		writer, err := zipWriter.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store})
+		if err != nil {
+			return nil, fmt.Errorf("error creating zip entry %s: %w", name, err)
+		}
+		if _, err := writer.Write(images[entry.ImageHref]); err != nil {
+			return nil, fmt.Errorf("error writing image data for %s: %w", name, err)
+		}
+	}
+
+	if err := zipWriter.Close(); err != nil {
+		return nil, fmt.Errorf("error closing zip writer: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func newEPUBPage(id, title string, page imaging.Page) epubPage {
+	width, height := epubDefaultWidth, epubDefaultHeight
+	if config, _, err := image.DecodeConfig(bytes.NewReader(page.Data)); err == nil && config.Width > 0 && config.Height > 0 {
+		width, height = config.Width, config.Height
+	}
+	return epubPage{
+		ID:        id,
+		Href:      "pages/" + id + ".xhtml",
+		ImageID:   id + "-image",
+		ImageHref: "images/" + id + "." + page.Format.Extension(),
+		MediaType: page.Format.MIMEType(),
+		Width:     width,
+		Height:    height,
+		Title:     title,
+	}
+}
+
+func pageDocument(page epubPage) epubPage {
+	page.ImageHref = "../" + page.ImageHref
+	return page
+}
+
+func epubIdentifier(chapters []cbzChapter) string {
+	ids := make([]string, 0, len(chapters))
+	for _, chapter := range chapters {
+		ids = append(ids, chapter.chapter.ID)
+	}
+	return fmt.Sprintf("urn:boox-serve:%x", sha1.Sum([]byte(strings.Join(ids, "\n"))))
+}
+
+func xmlText(value string) string {
+	var buf bytes.Buffer
+	if err := xml.EscapeText(&buf, []byte(value)); err != nil {
+		return ""
+	}
+	return buf.String()
+}
@@ -0,0 +1,129 @@
+package app
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/ssh-vom/boox-serve/internal/boox"
+	"github.com/ssh-vom/boox-serve/internal/imaging"
+	"github.com/ssh-vom/boox-serve/internal/providers/manga"
+)
+
+func readZipEntries(t *testing.T, data []byte) (*zip.Reader, map[string]string) {
+	t.Helper()
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("unable to read archive: %v", err)
+	}
+
+	entries := map[string]string{}
+	for _, file := range reader.File {
+		contents, err := file.Open()
+		if err != nil {
+			t.Fatalf("unable to open %s: %v", file.Name, err)
+		}
+		body, _ := io.ReadAll(contents)
+		contents.Close()
+		entries[file.Name] = string(body)
+	}
+	return reader, entries
+}
+
+func TestCreateEPUBWritesFixedLayoutPackage(t *testing.T) {
+	page := imaging.Page{Data: testImage(), Format: imaging.PNG}
+	cover := imaging.Page{Data: testImage(), Format: imaging.PNG}
+	data, err := createEPUB(epubBook{
+		identifier:  "urn:test",
+		title:       "Chapters 1-2",
+		series:      "Series & Co",
+		authors:     []string{"Author"},
+		language:    "en",
+		rightToLeft: true,
+		cover:       &cover,
+		chapters: []cbzChapter{
+			{name: "Chapter 1", images: []imaging.Page{page, page}},
+			{name: "Chapter 2", images: []imaging.Page{page}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	reader, entries := readZipEntries(t, data)
+	if first := reader.File[0]; first.Name != "mimetype" || first.Method != zip.Store || entries["mimetype"] != epubMimeType {
+		t.Fatalf("expected stored mimetype entry first, got %s (method %d)", first.Name, first.Method)
+	}
+
+	opf := entries["OEBPS/content.opf"]
+	var pkg struct {
+		Metadata struct {
+			Title  string   `xml:"title"`
+			Meta   []string `xml:"meta"`
+			Author []string `xml:"creator"`
+		} `xml:"metadata"`
+		Manifest []struct {
+			ID         string `xml:"id,attr"`
+			Properties string `xml:"properties,attr"`
+		} `xml:"manifest>item"`
+		Spine struct {
+			Direction string `xml:"page-progression-direction,attr"`
+			Items     []struct {
+				IDRef string `xml:"idref,attr"`
+			} `xml:"itemref"`
+		} `xml:"spine"`
+	}
+	if err := xml.Unmarshal([]byte(opf), &pkg); err != nil {
+		t.Fatalf("content.opf is not valid XML: %v", err)
+	}
+	if pkg.Metadata.Title != "Chapters 1-2" || len(pkg.Metadata.Author) != 1 {
+		t.Fatalf("unexpected metadata: %+v", pkg.Metadata)
+	}
+	if !strings.Contains(opf, "Series &amp; Co") || !strings.Contains(opf, `<meta property="rendition:layout">pre-paginated</meta>`) {
+		t.Fatalf("expected escaped series and fixed layout metadata:\n%s", opf)
+	}
+	if pkg.Spine.Direction != "rtl" || len(pkg.Spine.Items) != 4 || pkg.Spine.Items[0].IDRef != "cover" {
+		t.Fatalf("unexpected spine: %+v", pkg.Spine)
+	}
+
+	properties := map[string]string{}
+	for _, item := range pkg.Manifest {
+		properties[item.ID] = item.Properties
+	}
+	if properties["nav"] != "nav" || properties["cover-image"] != "cover-image" {
+		t.Fatalf("expected nav and cover image properties, got %v", properties)
+	}
+
+	nav := entries["OEBPS/nav.xhtml"]
+	if !strings.Contains(nav, `<a href="pages/page-0001.xhtml">Chapter 1</a>`) || !strings.Contains(nav, `<a href="pages/page-0003.xhtml">Chapter 2</a>`) {
+		t.Fatalf("expected a TOC entry per chapter:\n%s", nav)
+	}
+
+	pageDoc := entries["OEBPS/pages/page-0002.xhtml"]
+	if !strings.Contains(pageDoc, `content="width=1, height=1"`) || !strings.Contains(pageDoc, `src="../images/page-0002.png"`) {
+		t.Fatalf("unexpected page document:\n%s", pageDoc)
+	}
+	if _, ok := entries["OEBPS/images/cover.png"]; !ok {
+		t.Fatalf("expected cover image in archive")
+	}
+}
+
+func TestDownloadMangaChaptersUploadsEPUB(t *testing.T) {
+	chapters := []manga.Chapter{{ID: "c1", Number: "1"}, {ID: "c2", Number: "2"}}
+	device, client := newFakeDevice(t,
+		boox.LibraryBook{IDString: "book-1", Name: "Chapter 1.epub"},
+		boox.LibraryBook{IDString: "book-2", Name: "Chapter 2.cbz"},
+	)
+
+	_, err := DownloadAndUploadMangaChapters(context.Background(), client, fakeProvider{}, "", "Series", chapters, MangaUploadOptions{Format: OutputEPUB}, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if strings.Join(device.uploads, "|") != "Chapter 2.epub" {
+		t.Fatalf("expected only the missing chapter to be uploaded as EPUB, got %v", device.uploads)
+	}
+}
@@ -0,0 +1,158 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ssh-vom/boox-serve/internal/boox"
+	"github.com/ssh-vom/boox-serve/internal/imaging"
+	"github.com/ssh-vom/boox-serve/internal/providers/manga"
+)
+
+type ExistingMode string
+
+const (
+	ExistingSkip      ExistingMode = "skip"
+	ExistingOverwrite ExistingMode = "overwrite"
+	ExistingCopy      ExistingMode = "copy"
+)
+
+var ExistingModes = []ExistingMode{ExistingSkip, ExistingOverwrite, ExistingCopy}
+
+func ParseExistingMode(value string) (ExistingMode, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return ExistingSkip, nil
+	}
+	for _, mode := range ExistingModes {
+		if string(mode) == value {
+			return mode, nil
+		}
+	}
+	return "", fmt.Errorf("unknown existing chapter mode %q (expected skip, overwrite or copy)", value)
+}
+
+func (mode ExistingMode) Next() ExistingMode {
+	for index, candidate := range ExistingModes {
+		if candidate == mode {
+			return ExistingModes[(index+1)%len(ExistingModes)]
+		}
+	}
+	return ExistingSkip
+}
+
+type MangaUploadOptions struct {
+	Existing       ExistingMode    `json:"existing,omitempty"`
+	FolderTemplate string          `json:"folder_template,omitempty"`
+	Bundle         BundleMode      `json:"bundle,omitempty"`
+	BundleSize     int             `json:"bundle_size,omitempty"`
+	Format         OutputFormat    `json:"format,omitempty"`
+	CoverURL       string          `json:"cover_url,omitempty"`
+	Images         imaging.Options `json:"images"`
+}
+
+type deviceFiles map[string]string
+
+func (files deviceFiles) lookup(fileName string) (string, bool) {
+	id, ok := files[deviceFileKey(fileName)]
+	return id, ok
+}
+
+func (files deviceFiles) add(fileName, id string) {
+	files[deviceFileKey(fileName)] = id
+}
+
+func (files deviceFiles) batchCovers(chapter manga.Chapter, format OutputFormat) bool {
+	if chapter.Number == "" {
+		return false
+	}
+	for key := range files {
+		name := strings.TrimSuffix(key, strings.ToLower(format.Extension()))
+		numbers, ok := strings.CutPrefix(name, "chapters ")
+		if !ok {
+			continue
+		}
+		first, last, ok := strings.Cut(numbers, "-")
+		if !ok {
+			continue
+		}
+		if index := strings.LastIndex(last, " ("); index > 0 && strings.HasSuffix(last, ")") {
+			last = last[:index]
+		}
+		start, startErr := strconv.ParseFloat(first, 64)
+		end, endErr := strconv.ParseFloat(last, 64)
+		if startErr == nil && endErr == nil && chapter.NumericChapter >= start && chapter.NumericChapter <= end {
+			return true
+		}
+	}
+	return false
+}
+
+func (files deviceFiles) copyName(fileName string) string {
+	extension := ""
+	base := fileName
+	if index := strings.LastIndex(fileName, "."); index > 0 {
+		base, extension = fileName[:index], fileName[index:]
+	}
+	for copyNumber := 2; ; copyNumber++ {
+		candidate := fmt.Sprintf("%s (%d)%s", base, copyNumber, extension)
+		if _, ok := files.lookup(candidate); !ok {
+			return candidate
+		}
+	}
+}
+
+func deviceFileKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+func listDeviceFiles(ctx context.Context, sink Sink, folderID string) (deviceFiles, error) {
+	items, err := sink.ListFolder(ctx, folderID)
+	if err != nil {
+		return nil, err
+	}
+
+	files := deviceFiles{}
+	for _, item := range items {
+		if !item.IsFolder {
+			files.add(item.Title, item.ID)
+		}
+	}
+	return files, nil
+}
+
+func ExistingChapters(ctx context.Context, sink Sink, mangaTitle string, chapters []manga.Chapter, options MangaUploadOptions) (map[string]bool, error) {
+	present := map[string]bool{}
+	resolver := newFolderResolver(sink, false)
+	presenceOptions := options
+	if options.Bundle == BundleBatch {
+		presenceOptions.Bundle = BundleChapter
+	}
+	for _, bundle := range bundleChapters(chapters, presenceOptions) {
+		folderID, err := resolver.folder(ctx, FolderPath(options.FolderTemplate, mangaTitle, bundle.chapters[0]))
+		if errors.Is(err, boox.ErrFolderNotFound) {
+			continue
+		}
+		if err != nil {
+			return present, err
+		}
+
+		files, err := resolver.existing(ctx, folderID)
+		if err != nil {
+			return present, err
+		}
+		_, ok := files.lookup(bundle.fileName(options.Format))
+		if !ok && options.Bundle == BundleBatch {
+			ok = files.batchCovers(bundle.chapters[0], options.Format)
+		}
+		if ok {
+			for _, chapter := range bundle.chapters {
+				present[chapter.ID] = true
+			}
+		}
+	}
+	return present, nil
+}
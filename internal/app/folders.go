@@ -0,0 +1,120 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ssh-vom/boox-serve/internal/boox"
+	"github.com/ssh-vom/boox-serve/internal/providers/manga"
+)
+
+const DefaultFolderTemplate = "{series}"
+
+func FolderPath(template, series string, chapter manga.Chapter) []string {
+	if strings.TrimSpace(template) == "" {
+		template = DefaultFolderTemplate
+	}
+
+	values := map[string]string{
+		"{series}": strings.TrimSpace(series),
+		"{volume}": strings.TrimSpace(chapter.Volume),
+	}
+
+	path := []string{}
+	for _, segment := range strings.Split(template, "/") {
+		missing := false
+		for placeholder, value := range values {
+			if !strings.Contains(segment, placeholder) {
+				continue
+			}
+			if value == "" {
+				missing = true
+			}
+			segment = strings.ReplaceAll(segment, placeholder, value)
+		}
+		if missing || strings.TrimSpace(segment) == "" {
+			continue
+		}
+		path = append(path, sanitizeFileName(segment))
+	}
+	return path
+}
+
+type folderResolver struct {
+	sink    Sink
+	create  bool
+	folders map[string]string
+	missing map[string]bool
+	files   map[string]deviceFiles
+}
+
+func newFolderResolver(sink Sink, create bool) *folderResolver {
+	return &folderResolver{
+		sink:    sink,
+		create:  create,
+		folders: map[string]string{},
+		missing: map[string]bool{},
+		files:   map[string]deviceFiles{},
+	}
+}
+
+func (resolver *folderResolver) folder(ctx context.Context, path []string) (string, error) {
+	folderID := ""
+	for index, name := range path {
+		key := strings.Join(path[:index+1], "/")
+		if cachedID, ok := resolver.folders[key]; ok {
+			folderID = cachedID
+			continue
+		}
+		if resolver.missing[key] {
+			return "", fmt.Errorf("%w: %s", boox.ErrFolderNotFound, key)
+		}
+
+		nextID, err := resolver.sink.FindFolder(ctx, folderID, name)
+		if errors.Is(err, boox.ErrFolderNotFound) {
+			if !resolver.create {
+				resolver.missing[key] = true
+				return "", err
+			}
+			nextID, err = resolver.createFolder(ctx, folderID, name)
+		}
+		if err != nil {
+			return "", err
+		}
+
+		resolver.folders[key] = nextID
+		folderID = nextID
+	}
+	return folderID, nil
+}
+
+func (resolver *folderResolver) createFolder(ctx context.Context, parentID, name string) (string, error) {
+	var parent *string
+	if parentID != "" {
+		parent = &parentID
+	}
+	folderID, err := resolver.sink.CreateFolder(ctx, parent, name)
+	if err != nil {
+		return "", fmt.Errorf("unable to create folder %s: %w", name, err)
+	}
+	if folderID == "" {
+		return "", fmt.Errorf("unable to create folder %s: device returned no id", name)
+	}
+	resolver.files[folderID] = deviceFiles{}
+	return folderID, nil
+}
+
+func (resolver *folderResolver) existing(ctx context.Context, folderID string) (deviceFiles, error) {
+	if files, ok := resolver.files[folderID]; ok {
+		return files, nil
+	}
+
+	files, err := listDeviceFiles(ctx, resolver.sink, folderID)
+	if err != nil {
+		return nil, err
+	}
+	resolver.files[folderID] = files
+	return files, nil
+}
@@ -0,0 +1,28 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ssh-vom/boox-serve/internal/providers/manga"
+)
+
+func TestFolderPath(t *testing.T) {
+	tests := []struct {
+		template string
+		chapter  manga.Chapter
+		want     string
+	}{
+		{template: "", chapter: manga.Chapter{}, want: "One-Piece"},
+		{template: "Manga/{series}/Volume {volume}", chapter: manga.Chapter{Volume: "3"}, want: "Manga/One-Piece/Volume 3"},
+		{template: "Manga/{series}/Volume {volume}", chapter: manga.Chapter{}, want: "Manga/One-Piece"},
+		{template: "/Manga//{series}/", chapter: manga.Chapter{}, want: "Manga/One-Piece"},
+	}
+
+	for _, test := range tests {
+		path := FolderPath(test.template, "One/Piece", test.chapter)
+		if got := strings.Join(path, "/"); got != test.want {
+			t.Fatalf("FolderPath(%q) = %q, want %q", test.template, got, test.want)
+		}
+	}
+}
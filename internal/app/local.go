@@ -0,0 +1,186 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/ssh-vom/boox-serve/internal/boox"
+)
+
+const localTempPrefix = ".boox-serve-"
+
+type LocalSink struct {
+	root string
+}
+
+func NewLocalSink(root string) *LocalSink {
+	return &LocalSink{root: root}
+}
+
+func (sink *LocalSink) path(id string) (string, error) {
+	for _, segment := range strings.Split(id, "/") {
+		if segment == ".." {
+			return "", fmt.Errorf("invalid local path %q", id)
+		}
+	}
+	return filepath.Join(sink.root, filepath.FromSlash(id)), nil
+}
+
+func (sink *LocalSink) FindFolder(ctx context.Context, parentID, title string) (string, error) {
+	dir, err := sink.path(parentID)
+	if err != nil {
+		return "", err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("%w: %s", boox.ErrFolderNotFound, title)
+		}
+		return "", fmt.Errorf("unable to read %s: %w", dir, err)
+	}
+
+	title = strings.TrimSpace(title)
+	for _, entry := range entries {
+		if entry.IsDir() && strings.EqualFold(entry.Name(), title) {
+			return path.Join(parentID, entry.Name()), nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", boox.ErrFolderNotFound, title)
+}
+
+func (sink *LocalSink) CreateFolder(ctx context.Context, parentID *string, title string) (string, error) {
+	id := title
+	if parentID != nil {
+		id = path.Join(*parentID, title)
+	}
+	dir, err := sink.path(id)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("unable to create %s: %w", dir, err)
+	}
+	return id, nil
+}
+
+func (sink *LocalSink) ListFolder(ctx context.Context, folderID string) ([]boox.LibraryItem, error) {
+	dir, err := sink.path(folderID)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []boox.LibraryItem{}, nil
+		}
+		return nil, fmt.Errorf("unable to read %s: %w", dir, err)
+	}
+
+	items := make([]boox.LibraryItem, 0, len(entries))
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), localTempPrefix) {
+			continue
+		}
+		item := boox.LibraryItem{ID: path.Join(folderID, entry.Name()), Title: entry.Name(), IsFolder: entry.IsDir()}
+		if info, err := entry.Info(); err == nil {
+			item.Size = info.Size()
+			item.UpdatedAt = info.ModTime()
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (sink *LocalSink) DeleteItem(ctx context.Context, id string) error {
+	target, err := sink.path(id)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(target); err != nil {
+		return fmt.Errorf("unable to delete %s: %w", target, err)
+	}
+	return nil
+}
+
+func (sink *LocalSink) RenameItem(ctx context.Context, id, newName string) error {
+	source, err := sink.path(id)
+	if err != nil {
+		return err
+	}
+	if strings.ContainsAny(newName, `/\`) || newName == "" || newName == "." || newName == ".." {
+		return fmt.Errorf("invalid file name %q", newName)
+	}
+	if err := os.Rename(source, filepath.Join(filepath.Dir(source), newName)); err != nil {
+		return fmt.Errorf("unable to rename %s: %w", source, err)
+	}
+	return nil
+}
+
+func (sink *LocalSink) hashItem(ctx context.Context, id string) (string, error) {
+	target, err := sink.path(id)
+	if err != nil {
+		return "", err
+	}
+	return hashFile(target)
+}
+
+func (sink *LocalSink) UploadReader(ctx context.Context, parentID, fileName string, reader io.Reader, size int64, progress boox.UploadProgress) error {
+	dir, err := sink.path(parentID)
+	if err != nil {
+		return err
+	}
+	if strings.ContainsAny(fileName, `/\`) || fileName == "" || fileName == "." || fileName == ".." {
+		return fmt.Errorf("invalid file name %q", fileName)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("unable to create %s: %w", dir, err)
+	}
+
+	file, err := os.CreateTemp(dir, localTempPrefix+"*")
+	if err != nil {
+		return fmt.Errorf("unable to create file in %s: %w", dir, err)
+	}
+	defer os.Remove(file.Name())
+
+	buffer := make([]byte, 256*1024)
+	var written int64
+	for {
+		if err := ctx.Err(); err != nil {
+			file.Close()
+			return err
+		}
+		n, readErr := reader.Read(buffer)
+		if n > 0 {
+			if _, err := file.Write(buffer[:n]); err != nil {
+				file.Close()
+				return fmt.Errorf("unable to write %s: %w", fileName, err)
+			}
+			written += int64(n)
+			if progress != nil {
+				progress(written, size)
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			file.Close()
+			return fmt.Errorf("error reading %s: %w", fileName, readErr)
+		}
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("unable to write %s: %w", fileName, err)
+	}
+	if err := os.Rename(file.Name(), filepath.Join(dir, fileName)); err != nil {
+		return fmt.Errorf("unable to save %s: %w", fileName, err)
+	}
+	return nil
+}
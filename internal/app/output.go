@@ -0,0 +1,52 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+)
+
+type OutputFormat string
+
+const (
+	OutputCBZ  OutputFormat = "cbz"
+	OutputEPUB OutputFormat = "epub"
+	OutputPDF  OutputFormat = "pdf"
+)
+
+var OutputFormats = []OutputFormat{OutputCBZ, OutputEPUB, OutputPDF}
+
+func ParseOutputFormat(value string) (OutputFormat, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return OutputCBZ, nil
+	}
+	for _, format := range OutputFormats {
+		if string(format) == value {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("unknown output format %q (expected cbz, epub or pdf)", value)
+}
+
+func (format OutputFormat) Next() OutputFormat {
+	for index, candidate := range OutputFormats {
+		if candidate == format {
+			return OutputFormats[(index+1)%len(OutputFormats)]
+		}
+	}
+	return OutputEPUB
+}
+
+func (format OutputFormat) Extension() string {
+	if format == "" {
+		return "." + string(OutputCBZ)
+	}
+	return "." + string(format)
+}
+
+func (format OutputFormat) Label() string {
+	if format == "" {
+		return strings.ToUpper(string(OutputCBZ))
+	}
+	return strings.ToUpper(string(format))
+}
@@ -0,0 +1,201 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/ssh-vom/boox-serve/internal/imaging"
+)
+
+type pdfBook struct {
+	title       string
+	series      string
+	authors     []string
+	rightToLeft bool
+	quality     int
+	chapters    []cbzChapter
+}
+
+type pdfImage struct {
+	data       []byte
+	width      int
+	height     int
+	colorSpace string
+}
+
+type pdfWriter struct {
+	buf     bytes.Buffer
+	offsets []int
+}
+
+func (writer *pdfWriter) reserve() int {
+	writer.offsets = append(writer.offsets, 0)
+	return len(writer.offsets)
+}
+
+func (writer *pdfWriter) object(id int, body string) {
+	writer.offsets[id-1] = writer.buf.Len()
+	fmt.Fprintf(&writer.buf, "%d 0 obj\n%s\nendobj\n", id, body)
+}
+
+func (writer *pdfWriter) stream(id int, dictionary string, data []byte) {
+	writer.offsets[id-1] = writer.buf.Len()
+	fmt.Fprintf(&writer.buf, "%d 0 obj\n<< %s /Length %d >>\nstream\n", id, dictionary, len(data))
+	writer.buf.Write(data)
+	writer.buf.WriteString("\nendstream\nendobj\n")
+}
+
+func createPDF(book pdfBook) ([]byte, error) {
+	writer := &pdfWriter{}
+	writer.buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	catalogID := writer.reserve()
+	pagesID := writer.reserve()
+	outlinesID := writer.reserve()
+	infoID := writer.reserve()
+
+	pageIDs := []int{}
+	type bookmark struct {
+		id     int
+		title  string
+		pageID int
+	}
+	bookmarks := []bookmark{}
+
+	for _, chapter := range book.chapters {
+		for index, page := range chapter.images {
+			embedded, err := pdfJPEG(page, book.quality)
+			if err != nil {
+				return nil, fmt.Errorf("%s page %d: %w", chapter.name, index+1, err)
+			}
+
+			pageID := writer.reserve()
+			contentID := writer.reserve()
+			imageID := writer.reserve()
+			if index == 0 {
+				bookmarks = append(bookmarks, bookmark{id: writer.reserve(), title: chapter.name, pageID: pageID})
+			}
+
+			writer.stream(imageID, fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /%s /BitsPerComponent 8 /Filter /DCTDecode",
+				embedded.width, embedded.height, embedded.colorSpace), embedded.data)
+			writer.stream(contentID, "", []byte(fmt.Sprintf("q %d 0 0 %d 0 0 cm /Im0 Do Q", embedded.width, embedded.height)))
+			writer.object(pageID, fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %d %d] /Resources << /XObject << /Im0 %d 0 R >> >> /Contents %d 0 R >>",
+				pagesID, embedded.width, embedded.height, imageID, contentID))
+			pageIDs = append(pageIDs, pageID)
+		}
+	}
+	if len(pageIDs) == 0 {
+		return nil, fmt.Errorf("PDF has no pages")
+	}
+
+	kids := make([]string, 0, len(pageIDs))
+	for _, id := range pageIDs {
+		kids = append(kids, fmt.Sprintf("%d 0 R", id))
+	}
+	writer.object(pagesID, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pageIDs)))
+
+	for index, mark := range bookmarks {
+		links := ""
+		if index > 0 {
+			links += fmt.Sprintf(" /Prev %d 0 R", bookmarks[index-1].id)
+		}
+		if index < len(bookmarks)-1 {
+			links += fmt.Sprintf(" /Next %d 0 R", bookmarks[index+1].id)
+		}
+		writer.object(mark.id, fmt.Sprintf("<< /Title %s /Parent %d 0 R%s /Dest [%d 0 R /Fit] >>", pdfString(mark.title), outlinesID, links, mark.pageID))
+	}
+	writer.object(outlinesID, fmt.Sprintf("<< /Type /Outlines /First %d 0 R /Last %d 0 R /Count %d >>",
+		bookmarks[0].id, bookmarks[len(bookmarks)-1].id, len(bookmarks)))
+
+	preferences := ""
+	if book.rightToLeft {
+		preferences = " /ViewerPreferences << /Direction /R2L >>"
+	}
+	writer.object(catalogID, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R /Outlines %d 0 R /PageMode /UseOutlines%s >>", pagesID, outlinesID, preferences))
+
+	info := fmt.Sprintf("<< /Title %s /Creator (boox-serve)", pdfString(book.title))
+	if book.series != "" {
+		info += " /Subject " + pdfString(book.series)
+	}
+	if len(book.authors) > 0 {
+		info += " /Author " + pdfString(strings.Join(book.authors, ", "))
+	}
+	writer.object(infoID, info+" >>")
+
+	xrefOffset := writer.buf.Len()
+	fmt.Fprintf(&writer.buf, "xref\n0 %d\n0000000000 65535 f \n", len(writer.offsets)+1)
+	for _, offset := range writer.offsets {
+		fmt.Fprintf(&writer.buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&writer.buf, "trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(writer.offsets)+1, catalogID, infoID, xrefOffset)
+
+	return writer.buf.Bytes(), nil
+}
+
+func pdfJPEG(page imaging.Page, quality int) (pdfImage, error) {
+	data := page.Data
+	if page.Format == imaging.JPEG {
+		config, err := jpeg.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return pdfImage{}, fmt.Errorf("%w: %v", imaging.ErrCorruptImage, err)
+		}
+		if config.ColorModel != color.CMYKModel {
+			return newPDFImage(data, config), nil
+		}
+	}
+
+	source, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		if errors.Is(err, image.ErrFormat) {
+			return pdfImage{}, fmt.Errorf("%w: %s pages cannot be embedded in a PDF", imaging.ErrDecoderUnavailable, page.Format)
+		}
+		return pdfImage{}, fmt.Errorf("%w: %v", imaging.ErrCorruptImage, err)
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, source, &jpeg.Options{Quality: quality}); err != nil {
+		return pdfImage{}, fmt.Errorf("error encoding jpeg: %w", err)
+	}
+	config, err := jpeg.DecodeConfig(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		return pdfImage{}, fmt.Errorf("error reading encoded jpeg: %w", err)
+	}
+	return newPDFImage(buf.Bytes(), config), nil
+}
+
+func newPDFImage(data []byte, config image.Config) pdfImage {
+	colorSpace := "DeviceRGB"
+	if config.ColorModel == color.GrayModel {
+		colorSpace = "DeviceGray"
+	}
+	return pdfImage{data: data, width: config.Width, height: config.Height, colorSpace: colorSpace}
+}
+
+func pdfString(value string) string {
+	ascii := true
+	for _, r := range value {
+		if r < 0x20 || r > 0x7e {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		replacer := strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`)
+		return "(" + replacer.Replace(value) + ")"
+	}
+
+	var buf strings.Builder
+	buf.WriteString("<FEFF")
+	for _, unit := range utf16.Encode([]rune(value)) {
+		fmt.Fprintf(&buf, "%04X", unit)
+	}
+	buf.WriteString(">")
+	return buf.String()
+}
@@ -0,0 +1,100 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ssh-vom/boox-serve/internal/imaging"
+	"github.com/ssh-vom/boox-serve/internal/providers/manga"
+)
+
+func grayJPEG(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewGray(image.Rect(0, 0, 3, 4)), nil); err != nil {
+		t.Fatalf("unable to encode jpeg: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestCreatePDFEmbedsPagesWithBookmarks(t *testing.T) {
+	jpegPage := imaging.Page{Data: grayJPEG(t), Format: imaging.JPEG}
+	pngPage := imaging.Page{Data: testImage(), Format: imaging.PNG}
+
+	data, err := createPDF(pdfBook{
+		title:       "Volume 1",
+		series:      "Série",
+		authors:     []string{"Author"},
+		rightToLeft: true,
+		quality:     imaging.DefaultQuality,
+		chapters: []cbzChapter{
+			{name: "Chapter 1", images: []imaging.Page{jpegPage, pngPage}},
+			{name: "Chapter 2 (Extra)", images: []imaging.Page{jpegPage}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	pdf := string(data)
+	if !strings.HasPrefix(pdf, "%PDF-1.4\n") || !strings.HasSuffix(pdf, "%%EOF\n") {
+		t.Fatalf("missing PDF header or trailer")
+	}
+	if count := strings.Count(pdf, "/Type /Page "); count != 3 {
+		t.Fatalf("expected 3 pages, got %d", count)
+	}
+	if count := strings.Count(pdf, "/Filter /DCTDecode"); count != 3 {
+		t.Fatalf("expected every page embedded as JPEG, got %d", count)
+	}
+	if !strings.Contains(pdf, "/Width 3 /Height 4 /ColorSpace /DeviceGray") {
+		t.Fatalf("expected grayscale JPEG to be embedded as-is")
+	}
+	for _, want := range []string{"/Title (Chapter 1)", `/Title (Chapter 2 \(Extra\))`, "/Count 2", "/Direction /R2L", "/Subject <FEFF005300E9007200690065>"} {
+		if !strings.Contains(pdf, want) {
+			t.Fatalf("expected %q in PDF", want)
+		}
+	}
+
+	startxref := regexp.MustCompile(`startxref\n(\d+)\n`).FindStringSubmatch(pdf)
+	if startxref == nil {
+		t.Fatalf("missing startxref")
+	}
+	xrefOffset, _ := strconv.Atoi(startxref[1])
+	if !strings.HasPrefix(pdf[xrefOffset:], "xref\n") {
+		t.Fatalf("startxref does not point at the xref table")
+	}
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllStringSubmatch(pdf[xrefOffset:], -1)
+	for index, entry := range entries {
+		offset, _ := strconv.Atoi(entry[1])
+		if want := fmt.Sprintf("%d 0 obj", index+1); !strings.HasPrefix(pdf[offset:], want) {
+			t.Fatalf("xref entry %d points at %q", index+1, pdf[offset:offset+10])
+		}
+	}
+}
+
+func TestCreatePDFRejectsUndecodablePages(t *testing.T) {
+	webP := imaging.Page{Data: []byte("RIFF\x00\x00\x00\x00WEBPVP8L"), Format: imaging.WebP}
+	if _, err := createPDF(pdfBook{chapters: []cbzChapter{{name: "Chapter 1", images: []imaging.Page{webP}}}}); err == nil {
+		t.Fatalf("expected error for page without a decoder")
+	}
+}
+
+func TestDownloadMangaChaptersBundlesVolumeIntoPDF(t *testing.T) {
+	chapters := []manga.Chapter{{ID: "c1", Number: "1", Volume: "1"}, {ID: "c2", Number: "2", Volume: "1"}}
+	device, client := newFakeDevice(t)
+
+	options := MangaUploadOptions{Format: OutputPDF, Bundle: BundleVolume}
+	if _, err := DownloadAndUploadMangaChapters(context.Background(), client, fakeProvider{}, "", "Series", chapters, options, nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if strings.Join(device.uploads, "|") != "Volume 1.pdf" {
+		t.Fatalf("expected one PDF per volume, got %v", device.uploads)
+	}
+}
@@ -0,0 +1,472 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ssh-vom/boox-serve/internal/providers/manga"
+)
+
+type JobStatus string
+
+const (
+	JobPending   JobStatus = "pending"
+	JobRunning   JobStatus = "running"
+	JobPaused    JobStatus = "paused"
+	JobDone      JobStatus = "done"
+	JobFailed    JobStatus = "failed"
+	JobCancelled JobStatus = "cancelled"
+)
+
+var ErrJobNotFound = errors.New("job not found")
+
+type Job struct {
+	ID                string             `json:"id"`
+	MangaID           string             `json:"manga_id"`
+	Title             string             `json:"title"`
+	Chapters          []manga.Chapter    `json:"chapters"`
+	CompletedChapters []string           `json:"completed_chapters,omitempty"`
+	Options           MangaUploadOptions `json:"options"`
+	Status            JobStatus          `json:"status"`
+	Error             string             `json:"error,omitempty"`
+	CreatedAt         time.Time          `json:"created_at"`
+	UpdatedAt         time.Time          `json:"updated_at"`
+
+	Progress  ProgressUpdate `json:"-"`
+	requested JobStatus
+}
+
+func (job Job) RemainingChapters() []manga.Chapter {
+	completed := make(map[string]bool, len(job.CompletedChapters))
+	for _, id := range job.CompletedChapters {
+		completed[id] = true
+	}
+
+	remaining := []manga.Chapter{}
+	for _, chapter := range job.Chapters {
+		if !completed[chapter.ID] {
+			remaining = append(remaining, chapter)
+		}
+	}
+	return remaining
+}
+
+func (job Job) summary(run DownloadSummary) DownloadSummary {
+	completed := make(map[string]bool, len(job.CompletedChapters))
+	for _, id := range job.CompletedChapters {
+		completed[id] = true
+	}
+	wasSkipped := make(map[string]bool, len(run.Skipped))
+	for _, chapter := range run.Skipped {
+		wasSkipped[chapter.ID] = true
+	}
+	wasExisting := make(map[string]bool, len(run.Existing))
+	for _, chapter := range run.Existing {
+		wasExisting[chapter.ID] = true
+	}
+
+	summary := DownloadSummary{}
+	for _, chapter := range job.Chapters {
+		switch {
+		case wasExisting[chapter.ID]:
+			summary.Existing = append(summary.Existing, chapter)
+		case completed[chapter.ID]:
+			summary.Uploaded = append(summary.Uploaded, chapter)
+		case wasSkipped[chapter.ID]:
+			summary.Skipped = append(summary.Skipped, chapter)
+		default:
+			summary.Aborted = append(summary.Aborted, chapter)
+		}
+	}
+	return summary
+}
+
+func (job Job) Finished() bool {
+	return job.Status == JobDone || job.Status == JobFailed || job.Status == JobCancelled
+}
+
+type QueueEvent struct {
+	JobID  string
+	Update ProgressUpdate
+}
+
+type Queue struct {
+	mu       sync.Mutex
+	path     string
+	jobs     []*Job
+	sink     Sink
+	provider manga.Provider
+	cancel   context.CancelFunc
+	nextID   int
+	wake     chan struct{}
+	events   chan QueueEvent
+}
+
+func LoadQueue(path string) (*Queue, error) {
+	queue := &Queue{
+		path:   path,
+		wake:   make(chan struct{}, 1),
+		events: make(chan QueueEvent, 64),
+	}
+	if path == "" {
+		return queue, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return queue, nil
+		}
+		return queue, fmt.Errorf("unable to read queue: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &queue.jobs); err != nil {
+		return queue, fmt.Errorf("unable to parse queue: %w", err)
+	}
+
+	for _, job := range queue.jobs {
+		if job.Status == JobRunning {
+			job.Status = JobPending
+		}
+	}
+
+	return queue, nil
+}
+
+func (queue *Queue) Configure(sink Sink, provider manga.Provider) {
+	queue.mu.Lock()
+	queue.sink = sink
+	queue.provider = provider
+	queue.mu.Unlock()
+	queue.notify()
+}
+
+func (queue *Queue) Events() <-chan QueueEvent {
+	return queue.events
+}
+
+func (queue *Queue) Jobs() []Job {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+
+	jobs := make([]Job, 0, len(queue.jobs))
+	for _, job := range queue.jobs {
+		jobs = append(jobs, *job)
+	}
+	return jobs
+}
+
+func (queue *Queue) Enqueue(mangaID, title string, chapters []manga.Chapter, options MangaUploadOptions) (Job, error) {
+	if len(chapters) == 0 {
+		return Job{}, fmt.Errorf("no chapters selected")
+	}
+
+	queue.mu.Lock()
+	now := time.Now()
+	queue.nextID++
+	job := &Job{
+		ID:        strconv.FormatInt(now.UnixNano(), 36) + "-" + strconv.Itoa(queue.nextID),
+		MangaID:   mangaID,
+		Title:     title,
+		Chapters:  chapters,
+		Options:   options,
+		Status:    JobPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	queue.jobs = append(queue.jobs, job)
+	err := queue.saveLocked()
+	snapshot := *job
+	queue.mu.Unlock()
+
+	queue.notify()
+	return snapshot, err
+}
+
+func (queue *Queue) Pause(jobID string) error {
+	return queue.update(jobID, func(job *Job) {
+		switch job.Status {
+		case JobPending:
+			job.Status = JobPaused
+		case JobRunning:
+			job.requested = JobPaused
+			queue.cancelRunningLocked()
+		}
+	})
+}
+
+func (queue *Queue) Resume(jobID string) error {
+	return queue.update(jobID, func(job *Job) {
+		if job.Status == JobPaused {
+			job.Status = JobPending
+		}
+	})
+}
+
+func (queue *Queue) Retry(jobID string) error {
+	return queue.update(jobID, func(job *Job) {
+		if job.Status == JobFailed || job.Status == JobCancelled {
+			job.Status = JobPending
+			job.Error = ""
+		}
+	})
+}
+
+func (queue *Queue) Cancel(jobID string) error {
+	return queue.update(jobID, func(job *Job) {
+		switch job.Status {
+		case JobPending, JobPaused:
+			job.Status = JobCancelled
+		case JobRunning:
+			job.requested = JobCancelled
+			queue.cancelRunningLocked()
+		}
+	})
+}
+
+func (queue *Queue) Move(jobID string, offset int) error {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+
+	index := queue.indexLocked(jobID)
+	if index < 0 {
+		return ErrJobNotFound
+	}
+	target := index + offset
+	if target < 0 || target >= len(queue.jobs) {
+		return nil
+	}
+
+	queue.jobs[index], queue.jobs[target] = queue.jobs[target], queue.jobs[index]
+	return queue.saveLocked()
+}
+
+func (queue *Queue) ClearFinished() error {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+
+	kept := queue.jobs[:0]
+	for _, job := range queue.jobs {
+		if !job.Finished() {
+			kept = append(kept, job)
+		}
+	}
+	queue.jobs = kept
+	return queue.saveLocked()
+}
+
+type queuedRun struct {
+	ctx      context.Context
+	jobID    string
+	mangaID  string
+	title    string
+	chapters []manga.Chapter
+	options  MangaUploadOptions
+	sink     Sink
+	provider manga.Provider
+}
+
+func (queue *Queue) Run(ctx context.Context) {
+	for {
+		run := queue.next(ctx)
+		if run == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-queue.wake:
+				continue
+			}
+		}
+
+		queue.runJob(ctx, run)
+	}
+}
+
+func (queue *Queue) next(ctx context.Context) *queuedRun {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+
+	if ctx.Err() != nil || queue.sink == nil || queue.provider == nil {
+		return nil
+	}
+
+	for _, job := range queue.jobs {
+		if job.Status != JobPending {
+			continue
+		}
+		runCtx, cancel := context.WithCancel(ctx)
+		job.Status = JobRunning
+		job.Error = ""
+		job.requested = ""
+		job.Progress = ProgressUpdate{Message: "Starting download"}
+		job.UpdatedAt = time.Now()
+		queue.cancel = cancel
+		_ = queue.saveLocked()
+
+		return &queuedRun{
+			ctx:      runCtx,
+			jobID:    job.ID,
+			mangaID:  job.MangaID,
+			title:    job.Title,
+			chapters: job.RemainingChapters(),
+			options:  job.Options,
+			sink:     queue.sink,
+			provider: queue.provider,
+		}
+	}
+
+	return nil
+}
+
+func (queue *Queue) runJob(ctx context.Context, run *queuedRun) {
+	updates := make(chan ProgressUpdate)
+	done := make(chan struct{})
+	go func() {
+		for update := range updates {
+			queue.record(run.jobID, update)
+		}
+		close(done)
+	}()
+
+	var summary DownloadSummary
+	var err error
+	if len(run.chapters) > 0 {
+		summary, err = DownloadAndUploadMangaChapters(run.ctx, run.sink, run.provider, run.mangaID, run.title, run.chapters, run.options, updates)
+	}
+	close(updates)
+	<-done
+
+	queue.finish(ctx, run.jobID, summary, err)
+}
+
+func (queue *Queue) record(jobID string, update ProgressUpdate) {
+	queue.mu.Lock()
+	if job := queue.findLocked(jobID); job != nil {
+		job.Progress = update
+		if len(update.UploadedChapters) > 0 {
+			job.CompletedChapters = append(job.CompletedChapters, update.UploadedChapters...)
+			job.UpdatedAt = time.Now()
+			_ = queue.saveLocked()
+		}
+	}
+	queue.mu.Unlock()
+
+	queue.events <- QueueEvent{JobID: jobID, Update: update}
+}
+
+func (queue *Queue) finish(ctx context.Context, jobID string, runSummary DownloadSummary, err error) {
+	queue.mu.Lock()
+	if queue.cancel != nil {
+		queue.cancel()
+	}
+	queue.cancel = nil
+
+	job := queue.findLocked(jobID)
+	if job == nil {
+		queue.mu.Unlock()
+		return
+	}
+
+	switch {
+	case job.requested != "":
+		job.Status = job.requested
+		err = nil
+	case err == nil:
+		job.Status = JobDone
+	case ctx.Err() != nil:
+		job.Status = JobPending
+	default:
+		job.Status = JobFailed
+		job.Error = err.Error()
+	}
+	job.requested = ""
+	job.UpdatedAt = time.Now()
+	summary := job.summary(runSummary)
+	job.Progress = ProgressUpdate{Done: true, Err: err, Summary: &summary, Message: "Job " + string(job.Status)}
+	update := job.Progress
+	_ = queue.saveLocked()
+	queue.mu.Unlock()
+
+	if ctx.Err() != nil {
+		return
+	}
+	queue.events <- QueueEvent{JobID: jobID, Update: update}
+}
+
+func (queue *Queue) update(jobID string, change func(job *Job)) error {
+	queue.mu.Lock()
+	job := queue.findLocked(jobID)
+	if job == nil {
+		queue.mu.Unlock()
+		return ErrJobNotFound
+	}
+
+	change(job)
+	job.UpdatedAt = time.Now()
+	err := queue.saveLocked()
+	queue.mu.Unlock()
+
+	queue.notify()
+	return err
+}
+
+func (queue *Queue) cancelRunningLocked() {
+	if queue.cancel != nil {
+		queue.cancel()
+	}
+}
+
+func (queue *Queue) notify() {
+	select {
+	case queue.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (queue *Queue) findLocked(jobID string) *Job {
+	if index := queue.indexLocked(jobID); index >= 0 {
+		return queue.jobs[index]
+	}
+	return nil
+}
+
+func (queue *Queue) indexLocked(jobID string) int {
+	for index, job := range queue.jobs {
+		if job.ID == jobID {
+			return index
+		}
+	}
+	return -1
+}
+
+func (queue *Queue) saveLocked() error {
+	if queue.path == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(queue.path), 0o755); err != nil {
+		return fmt.Errorf("unable to create queue dir: %w", err)
+	}
+
+	data, err := json.MarshalIndent(queue.jobs, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to marshal queue: %w", err)
+	}
+
+	tempPath := queue.path + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0o644); err != nil {
+		return fmt.Errorf("unable to write queue: %w", err)
+	}
+	if err := os.Rename(tempPath, queue.path); err != nil {
+		return fmt.Errorf("unable to write queue: %w", err)
+	}
+
+	return nil
+}
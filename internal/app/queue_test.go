@@ -0,0 +1,140 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ssh-vom/boox-serve/internal/boox"
+	"github.com/ssh-vom/boox-serve/internal/providers/manga"
+)
+
+type fakeProvider struct{}
+
+func (fakeProvider) Search(ctx context.Context, query string, filter manga.SearchFilter, offset int) (manga.SearchPage, error) {
+	return manga.SearchPage{}, nil
+}
+
+func (fakeProvider) FetchSeries(ctx context.Context, mangaID string) (manga.Series, error) {
+	return manga.Series{ID: mangaID, Title: "Series", Authors: []string{"Author"}, Language: "ja"}, nil
+}
+
+func (provider fakeProvider) FetchDetails(ctx context.Context, mangaID string) (manga.SeriesDetails, error) {
+	series, err := provider.FetchSeries(ctx, mangaID)
+	return manga.SeriesDetails{Series: series}, err
+}
+
+func (fakeProvider) FetchChapters(ctx context.Context, mangaID string) ([]manga.Chapter, error) {
+	return nil, nil
+}
+
+func (fakeProvider) DownloadChapterImages(ctx context.Context, chapter manga.Chapter, progress manga.PageProgress) ([][]byte, error) {
+	return [][]byte{testImage()}, nil
+}
+
+func (fakeProvider) FetchCover(ctx context.Context, coverURL string) ([]byte, error) {
+	return nil, nil
+}
+
+func testImage() []byte {
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 1)))
+	return buf.Bytes()
+}
+
+func newFakeBoox(t *testing.T) *boox.Client {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/library" {
+			return
+		}
+		if r.Method == http.MethodPost {
+			json.NewEncoder(w).Encode(boox.FolderCreationResponse{ID: "folder"})
+			return
+		}
+		json.NewEncoder(w).Encode(boox.LibraryResponse{})
+	}))
+	t.Cleanup(server.Close)
+	return boox.NewClient(server.URL, server.Client())
+}
+
+func TestQueueRunsJobsAndPersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.json")
+	queue, err := LoadQueue(path)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	queue.Configure(newFakeBoox(t), fakeProvider{})
+
+	chapters := []manga.Chapter{{ID: "c1", Number: "1"}, {ID: "c2", Number: "2"}}
+	job, err := queue.Enqueue("manga", "Series", chapters, MangaUploadOptions{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go queue.Run(ctx)
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case event := <-queue.Events():
+			if event.JobID != job.ID || !event.Update.Done {
+				continue
+			}
+			if event.Update.Err != nil {
+				t.Fatalf("expected job to succeed, got %v", event.Update.Err)
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for job")
+		}
+		break
+	}
+
+	reloaded, err := LoadQueue(path)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	jobs := reloaded.Jobs()
+	if len(jobs) != 1 || jobs[0].Status != JobDone || len(jobs[0].CompletedChapters) != 2 {
+		t.Fatalf("unexpected persisted jobs: %+v", jobs)
+	}
+}
+
+func TestLoadQueueResumesInterruptedJobs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.json")
+	jobs := []Job{{
+		ID:                "job",
+		Title:             "Series",
+		Chapters:          []manga.Chapter{{ID: "c1"}, {ID: "c2"}},
+		CompletedChapters: []string{"c1"},
+		Status:            JobRunning,
+	}}
+	data, _ := json.Marshal(jobs)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("unable to write queue: %v", err)
+	}
+
+	queue, err := LoadQueue(path)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	loaded := queue.Jobs()
+	if loaded[0].Status != JobPending {
+		t.Fatalf("expected interrupted job to be pending, got %s", loaded[0].Status)
+	}
+	remaining := loaded[0].RemainingChapters()
+	if len(remaining) != 1 || remaining[0].ID != "c2" {
+		t.Fatalf("unexpected remaining chapters: %+v", remaining)
+	}
+}
@@ -0,0 +1,322 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/ssh-vom/boox-serve/internal/boox"
+)
+
+type Sink interface {
+	FindFolder(ctx context.Context, parentID, title string) (string, error)
+	CreateFolder(ctx context.Context, parentID *string, title string) (string, error)
+	ListFolder(ctx context.Context, folderID string) ([]boox.LibraryItem, error)
+	DeleteItem(ctx context.Context, id string) error
+	UploadReader(ctx context.Context, parentID, fileName string, reader io.Reader, size int64, progress boox.UploadProgress) error
+}
+
+type Target string
+
+const (
+	TargetBoox  Target = "boox"
+	TargetLocal Target = "local"
+	TargetBoth  Target = "both"
+)
+
+func ParseTarget(value string) (Target, error) {
+	switch target := Target(strings.ToLower(strings.TrimSpace(value))); target {
+	case "", TargetBoox:
+		return TargetBoox, nil
+	case TargetLocal, TargetBoth:
+		return target, nil
+	default:
+		return "", fmt.Errorf("unknown output target %q (expected boox, local or both)", value)
+	}
+}
+
+func (target Target) UsesBoox() bool {
+	return target != TargetLocal
+}
+
+func NewSink(target Target, booxClient *boox.Client, localDir string) (Sink, error) {
+	var local Sink
+	if target == TargetLocal || target == TargetBoth {
+		if strings.TrimSpace(localDir) == "" {
+			return nil, errors.New("local output directory not configured")
+		}
+		local = NewLocalSink(localDir)
+	}
+	if target.UsesBoox() && booxClient == nil {
+		return nil, errors.New("boox device not configured")
+	}
+
+	switch target {
+	case TargetLocal:
+		return local, nil
+	case TargetBoth:
+		return newMultiSink(booxClient, local), nil
+	default:
+		return booxClient, nil
+	}
+}
+
+type itemReplacer interface {
+	replace(ctx context.Context, parentID, fileName, oldID string, reader io.Reader, size int64, progress boox.UploadProgress) error
+}
+
+func replaceReader(ctx context.Context, sink Sink, parentID, fileName, oldID string, reader io.Reader, size int64, progress boox.UploadProgress) error {
+	if replacer, ok := sink.(itemReplacer); ok && oldID != "" {
+		return replacer.replace(ctx, parentID, fileName, oldID, reader, size, progress)
+	}
+	if err := sink.UploadReader(ctx, parentID, fileName, reader, size, progress); err != nil {
+		return err
+	}
+	if oldID == "" {
+		return nil
+	}
+
+	items, err := sink.ListFolder(ctx, parentID)
+	if err != nil {
+		return fmt.Errorf("unable to confirm upload of %s: %w", fileName, err)
+	}
+	for _, item := range items {
+		if !item.IsFolder && item.ID != oldID && strings.EqualFold(item.Title, fileName) {
+			if err := sink.DeleteItem(ctx, oldID); err != nil {
+				return fmt.Errorf("error removing previous %s: %w", fileName, err)
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+const multiSinkSeparator = "\x1f"
+
+type multiSink struct {
+	sinks   []Sink
+	mu      sync.Mutex
+	present map[string][]map[string]bool
+	located map[multiSinkItem]multiSinkLocation
+}
+
+type multiSinkItem struct {
+	index int
+	id    string
+}
+
+type multiSinkLocation struct {
+	folderID string
+	key      string
+}
+
+func newMultiSink(sinks ...Sink) *multiSink {
+	return &multiSink{sinks: sinks, present: map[string][]map[string]bool{}, located: map[multiSinkItem]multiSinkLocation{}}
+}
+
+func (sink *multiSink) split(id string) []string {
+	if id == "" {
+		return make([]string, len(sink.sinks))
+	}
+	parts := strings.Split(id, multiSinkSeparator)
+	if len(parts) != len(sink.sinks) {
+		return make([]string, len(sink.sinks))
+	}
+	return parts
+}
+
+func (sink *multiSink) join(parts []string) string {
+	for _, part := range parts {
+		if part != "" {
+			return strings.Join(parts, multiSinkSeparator)
+		}
+	}
+	return ""
+}
+
+func (sink *multiSink) FindFolder(ctx context.Context, parentID, title string) (string, error) {
+	parents := sink.split(parentID)
+	ids := make([]string, len(sink.sinks))
+	for index, target := range sink.sinks {
+		id, err := target.FindFolder(ctx, parents[index], title)
+		if err != nil {
+			return "", err
+		}
+		ids[index] = id
+	}
+	return sink.join(ids), nil
+}
+
+func (sink *multiSink) CreateFolder(ctx context.Context, parentID *string, title string) (string, error) {
+	parents := make([]string, len(sink.sinks))
+	if parentID != nil {
+		parents = sink.split(*parentID)
+	}
+
+	ids := make([]string, len(sink.sinks))
+	for index, target := range sink.sinks {
+		id, err := target.FindFolder(ctx, parents[index], title)
+		if errors.Is(err, boox.ErrFolderNotFound) {
+			var parent *string
+			if parents[index] != "" {
+				parent = &parents[index]
+			}
+			id, err = target.CreateFolder(ctx, parent, title)
+		}
+		if err != nil {
+			return "", err
+		}
+		ids[index] = id
+	}
+	return sink.join(ids), nil
+}
+
+func (sink *multiSink) ListFolder(ctx context.Context, folderID string) ([]boox.LibraryItem, error) {
+	folders := sink.split(folderID)
+	present := make([]map[string]bool, len(sink.sinks))
+	located := map[multiSinkItem]multiSinkLocation{}
+	found := map[string][]string{}
+	first := map[string]boox.LibraryItem{}
+	order := []string{}
+
+	for index, target := range sink.sinks {
+		items, err := target.ListFolder(ctx, folders[index])
+		if err != nil {
+			return nil, err
+		}
+		present[index] = map[string]bool{}
+		for _, item := range items {
+			key := deviceFileKey(item.Title)
+			if item.IsFolder {
+				key = "/" + key
+			} else {
+				present[index][key] = true
+				located[multiSinkItem{index: index, id: item.ID}] = multiSinkLocation{folderID: folderID, key: key}
+			}
+			if _, ok := found[key]; !ok {
+				found[key] = make([]string, len(sink.sinks))
+				first[key] = item
+				order = append(order, key)
+			}
+			found[key][index] = item.ID
+		}
+	}
+
+	sink.mu.Lock()
+	sink.present[folderID] = present
+	for item, location := range located {
+		sink.located[item] = location
+	}
+	sink.mu.Unlock()
+
+	items := []boox.LibraryItem{}
+	for _, key := range order {
+		ids := found[key]
+		complete := true
+		for _, id := range ids {
+			if id == "" {
+				complete = false
+				break
+			}
+		}
+		if complete {
+			item := first[key]
+			item.ID = sink.join(ids)
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+func (sink *multiSink) DeleteItem(ctx context.Context, id string) error {
+	for index, part := range sink.split(id) {
+		if part == "" {
+			continue
+		}
+		if err := sink.sinks[index].DeleteItem(ctx, part); err != nil {
+			return err
+		}
+		sink.forget(index, part)
+	}
+	return nil
+}
+
+func (sink *multiSink) forget(index int, id string) {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	item := multiSinkItem{index: index, id: id}
+	location, ok := sink.located[item]
+	if !ok {
+		return
+	}
+	delete(sink.located, item)
+	if present := sink.present[location.folderID]; present != nil {
+		delete(present[index], location.key)
+	}
+}
+
+func (sink *multiSink) UploadReader(ctx context.Context, parentID, fileName string, reader io.Reader, size int64, progress boox.UploadProgress) error {
+	return sink.upload(ctx, parentID, fileName, make([]string, len(sink.sinks)), reader, size, progress)
+}
+
+func (sink *multiSink) replace(ctx context.Context, parentID, fileName, oldID string, reader io.Reader, size int64, progress boox.UploadProgress) error {
+	return sink.upload(ctx, parentID, fileName, sink.split(oldID), reader, size, progress)
+}
+
+func (sink *multiSink) upload(ctx context.Context, parentID, fileName string, oldIDs []string, reader io.Reader, size int64, progress boox.UploadProgress) error {
+	key := deviceFileKey(fileName)
+	sink.mu.Lock()
+	present := sink.present[parentID]
+	sink.mu.Unlock()
+
+	targets := []int{}
+	for index := range sink.sinks {
+		if oldIDs[index] != "" || present == nil || !present[index][key] {
+			targets = append(targets, index)
+		}
+	}
+
+	source := func() io.Reader { return reader }
+	if len(targets) > 1 {
+		spooled, err := os.CreateTemp("", "boox-serve-upload-*")
+		if err != nil {
+			return fmt.Errorf("error buffering %s: %w", fileName, err)
+		}
+		defer os.Remove(spooled.Name())
+		defer spooled.Close()
+		written, err := io.Copy(spooled, reader)
+		if err != nil {
+			return fmt.Errorf("error reading %s: %w", fileName, err)
+		}
+		size = written
+		source = func() io.Reader { return io.NewSectionReader(spooled, 0, written) }
+	}
+
+	parents := sink.split(parentID)
+	total := size
+	if size > 0 {
+		total = size * int64(len(targets))
+	}
+	for done, index := range targets {
+		offset := size * int64(done)
+		var targetProgress boox.UploadProgress
+		if progress != nil {
+			targetProgress = func(sent, _ int64) {
+				progress(offset+sent, total)
+			}
+		}
+		if err := replaceReader(ctx, sink.sinks[index], parents[index], fileName, oldIDs[index], source(), size, targetProgress); err != nil {
+			return err
+		}
+		if present != nil {
+			sink.mu.Lock()
+			present[index][key] = true
+			sink.mu.Unlock()
+		}
+	}
+	return nil
+}
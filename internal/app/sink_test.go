@@ -0,0 +1,219 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ssh-vom/boox-serve/internal/boox"
+	"github.com/ssh-vom/boox-serve/internal/providers/manga"
+)
+
+func TestParseTarget(t *testing.T) {
+	tests := map[string]Target{"": TargetBoox, "boox": TargetBoox, " Local ": TargetLocal, "both": TargetBoth}
+	for value, want := range tests {
+		got, err := ParseTarget(value)
+		if err != nil || got != want {
+			t.Fatalf("ParseTarget(%q) = %q, %v; want %q", value, got, err, want)
+		}
+	}
+	if _, err := ParseTarget("ftp"); err == nil {
+		t.Fatalf("expected error for unknown target")
+	}
+	if _, err := NewSink(TargetLocal, nil, ""); err == nil {
+		t.Fatalf("expected error for local target without a directory")
+	}
+	if _, err := NewSink(TargetBoth, nil, t.TempDir()); err == nil {
+		t.Fatalf("expected error for both target without a boox client")
+	}
+}
+
+func TestLocalSinkFolders(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	sink := NewLocalSink(root)
+
+	if _, err := sink.FindFolder(ctx, "", "Manga"); !errors.Is(err, boox.ErrFolderNotFound) {
+		t.Fatalf("expected ErrFolderNotFound, got %v", err)
+	}
+
+	resolver := newFolderResolver(sink, true)
+	folderID, err := resolver.folder(ctx, []string{"Manga", "One Piece"})
+	if err != nil {
+		t.Fatalf("folder: %v", err)
+	}
+	if folderID != "Manga/One Piece" {
+		t.Fatalf("folder ID = %q", folderID)
+	}
+
+	found, err := sink.FindFolder(ctx, "Manga", "one piece")
+	if err != nil || found != folderID {
+		t.Fatalf("FindFolder = %q, %v", found, err)
+	}
+
+	if _, err := sink.CreateFolder(ctx, nil, ".."); err == nil {
+		t.Fatalf("expected error for folder outside the root")
+	}
+}
+
+func TestLocalSinkUploadListDelete(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	sink := NewLocalSink(root)
+
+	var reported int64
+	err := sink.UploadReader(ctx, "Manga", "Chapter 1.cbz", strings.NewReader("archive"), 7, func(sent, total int64) {
+		reported = sent
+	})
+	if err != nil {
+		t.Fatalf("UploadReader: %v", err)
+	}
+	if reported != 7 {
+		t.Fatalf("progress = %d, want 7", reported)
+	}
+
+	data, err := os.ReadFile(filepath.Join(root, "Manga", "Chapter 1.cbz"))
+	if err != nil || string(data) != "archive" {
+		t.Fatalf("file = %q, %v", data, err)
+	}
+
+	files, err := listDeviceFiles(ctx, sink, "Manga")
+	if err != nil {
+		t.Fatalf("listDeviceFiles: %v", err)
+	}
+	id, ok := files.lookup("chapter 1.CBZ")
+	if !ok || id != "Manga/Chapter 1.cbz" {
+		t.Fatalf("lookup = %q, %v", id, ok)
+	}
+	if _, ok := files.lookup("Chapter 1.epub"); ok {
+		t.Fatalf("expected an epub lookup not to match the cbz")
+	}
+
+	if err := sink.DeleteItem(ctx, id); err != nil {
+		t.Fatalf("DeleteItem: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "Manga", "Chapter 1.cbz")); !os.IsNotExist(err) {
+		t.Fatalf("expected file to be deleted, got %v", err)
+	}
+
+	if err := sink.UploadReader(ctx, "Manga", "../escape.cbz", strings.NewReader("x"), 1, nil); err == nil {
+		t.Fatalf("expected error for file name with a path")
+	}
+}
+
+func TestMultiSinkUploadsMissingTargetsOnly(t *testing.T) {
+	ctx := context.Background()
+	firstRoot, secondRoot := t.TempDir(), t.TempDir()
+	first, second := NewLocalSink(firstRoot), NewLocalSink(secondRoot)
+	sink := newMultiSink(first, second)
+
+	folderID, err := newFolderResolver(sink, true).folder(ctx, []string{"Manga"})
+	if err != nil {
+		t.Fatalf("folder: %v", err)
+	}
+	if err := first.UploadReader(ctx, "Manga", "Chapter 1.cbz", strings.NewReader("original"), 8, nil); err != nil {
+		t.Fatalf("seed upload: %v", err)
+	}
+
+	files, err := listDeviceFiles(ctx, sink, folderID)
+	if err != nil {
+		t.Fatalf("listDeviceFiles: %v", err)
+	}
+	if _, ok := files.lookup("Chapter 1.cbz"); ok {
+		t.Fatalf("chapter present on one target only should not count as existing")
+	}
+
+	var sent, total int64
+	err = sink.UploadReader(ctx, folderID, "Chapter 1.cbz", strings.NewReader("updated"), 7, func(done, size int64) {
+		sent, total = done, size
+	})
+	if err != nil {
+		t.Fatalf("UploadReader: %v", err)
+	}
+	if sent != 7 || total != 7 {
+		t.Fatalf("progress = %d/%d, want 7/7", sent, total)
+	}
+
+	if data, _ := os.ReadFile(filepath.Join(firstRoot, "Manga", "Chapter 1.cbz")); string(data) != "original" {
+		t.Fatalf("first target rewritten: %q", data)
+	}
+	if data, _ := os.ReadFile(filepath.Join(secondRoot, "Manga", "Chapter 1.cbz")); string(data) != "updated" {
+		t.Fatalf("second target = %q", data)
+	}
+
+	files, err = listDeviceFiles(ctx, sink, folderID)
+	if err != nil {
+		t.Fatalf("listDeviceFiles: %v", err)
+	}
+	id, ok := files.lookup("Chapter 1.cbz")
+	if !ok {
+		t.Fatalf("expected chapter to exist on both targets")
+	}
+	if err := sink.DeleteItem(ctx, id); err != nil {
+		t.Fatalf("DeleteItem: %v", err)
+	}
+	for _, root := range []string{firstRoot, secondRoot} {
+		if _, err := os.Stat(filepath.Join(root, "Manga", "Chapter 1.cbz")); !os.IsNotExist(err) {
+			t.Fatalf("expected file removed from %s, got %v", root, err)
+		}
+	}
+
+	if err := sink.UploadReader(ctx, folderID, "Chapter 1.cbz", strings.NewReader("again"), 5, nil); err != nil {
+		t.Fatalf("UploadReader after delete: %v", err)
+	}
+	for _, root := range []string{firstRoot, secondRoot} {
+		if data, _ := os.ReadFile(filepath.Join(root, "Manga", "Chapter 1.cbz")); string(data) != "again" {
+			t.Fatalf("expected re-upload to %s after delete, got %q", root, data)
+		}
+	}
+}
+
+func TestMultiSinkOverwritesChapterOnBothTargets(t *testing.T) {
+	ctx := context.Background()
+	device, client := newFakeDevice(t, boox.LibraryBook{IDString: "book-1", Name: "Chapter 1.cbz"})
+	root := t.TempDir()
+	local := NewLocalSink(root)
+	if _, err := newFolderResolver(local, true).folder(ctx, []string{"Series"}); err != nil {
+		t.Fatalf("folder: %v", err)
+	}
+	if err := local.UploadReader(ctx, "Series", "Chapter 1.cbz", strings.NewReader("original"), 8, nil); err != nil {
+		t.Fatalf("seed upload: %v", err)
+	}
+
+	chapters := []manga.Chapter{{ID: "c1", Number: "1"}}
+	_, err := DownloadAndUploadMangaChapters(ctx, newMultiSink(client, local), fakeProvider{}, "", "Series", chapters, MangaUploadOptions{Existing: ExistingOverwrite}, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if strings.Join(device.uploads, "|") != "Chapter 1.cbz" || strings.Join(device.deleted, "|") != "book-1" {
+		t.Fatalf("unexpected device changes: uploads %v, deletes %v", device.uploads, device.deleted)
+	}
+	if data, _ := os.ReadFile(filepath.Join(root, "Series", "Chapter 1.cbz")); string(data) == "original" {
+		t.Fatalf("expected local chapter to be overwritten")
+	}
+}
+
+func TestMultiSinkSpoolsUploadForEveryTarget(t *testing.T) {
+	ctx := context.Background()
+	firstRoot, secondRoot := t.TempDir(), t.TempDir()
+	sink := newMultiSink(NewLocalSink(firstRoot), NewLocalSink(secondRoot))
+
+	var sent, total int64
+	err := sink.UploadReader(ctx, "", "Book.pdf", strings.NewReader("streamed"), 8, func(done, size int64) {
+		sent, total = done, size
+	})
+	if err != nil {
+		t.Fatalf("UploadReader: %v", err)
+	}
+	if sent != 16 || total != 16 {
+		t.Fatalf("progress = %d/%d, want 16/16", sent, total)
+	}
+	for _, root := range []string{firstRoot, secondRoot} {
+		if data, _ := os.ReadFile(filepath.Join(root, "Book.pdf")); string(data) != "streamed" {
+			t.Fatalf("%s = %q", root, data)
+		}
+	}
+}
@@ -0,0 +1,352 @@
+package app
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ssh-vom/boox-serve/internal/boox"
+)
+
+type SyncTarget interface {
+	Sink
+	RenameItem(ctx context.Context, id, newName string) error
+}
+
+type SyncActionKind string
+
+const (
+	SyncUpload  SyncActionKind = "upload"
+	SyncReplace SyncActionKind = "replace"
+	SyncRename  SyncActionKind = "rename"
+	SyncDelete  SyncActionKind = "delete"
+)
+
+type itemHasher interface {
+	hashItem(ctx context.Context, id string) (string, error)
+}
+
+type SyncOptions struct {
+	Folder string
+	Delete bool
+}
+
+type SyncAction struct {
+	Kind SyncActionKind `json:"kind"`
+	Path string         `json:"path"`
+	From string         `json:"from,omitempty"`
+	Size int64          `json:"size,omitempty"`
+
+	folder    []string
+	localPath string
+	itemID    string
+}
+
+func (action SyncAction) String() string {
+	switch action.Kind {
+	case SyncRename:
+		return fmt.Sprintf("rename %s -> %s", action.From, action.Path)
+	default:
+		return fmt.Sprintf("%s %s", action.Kind, action.Path)
+	}
+}
+
+type SyncPlan struct {
+	Actions   []SyncAction `json:"actions"`
+	Unchanged int          `json:"unchanged"`
+}
+
+type localFile struct {
+	name    string
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+func PlanSync(ctx context.Context, target SyncTarget, localDir string, options SyncOptions) (SyncPlan, error) {
+	plan := SyncPlan{}
+	info, err := os.Stat(localDir)
+	if err != nil {
+		return plan, fmt.Errorf("unable to read %s: %w", localDir, err)
+	}
+	if !info.IsDir() {
+		return plan, fmt.Errorf("%s is not a directory", localDir)
+	}
+
+	root := splitFolder(options.Folder)
+	directories, err := localTree(localDir)
+	if err != nil {
+		return plan, err
+	}
+
+	resolver := newFolderResolver(target, false)
+	sameContent := contentMatcher(ctx, target)
+	relatives := make([]string, 0, len(directories))
+	for relative := range directories {
+		relatives = append(relatives, relative)
+	}
+	sort.Strings(relatives)
+
+	for _, relative := range relatives {
+		if err := ctx.Err(); err != nil {
+			return plan, err
+		}
+		folder := append(append([]string{}, root...), splitFolder(relative)...)
+
+		folderID := ""
+		if len(folder) > 0 {
+			folderID, err = resolver.folder(ctx, folder)
+			if errors.Is(err, boox.ErrFolderNotFound) {
+				planFolder(&plan, folder, directories[relative], nil, sameContent, options)
+				continue
+			}
+			if err != nil {
+				return plan, err
+			}
+		}
+		items, err := target.ListFolder(ctx, folderID)
+		if err != nil {
+			return plan, err
+		}
+
+		planFolder(&plan, folder, directories[relative], items, sameContent, options)
+	}
+	return plan, nil
+}
+
+func contentMatcher(ctx context.Context, target SyncTarget) func(localFile, boox.LibraryItem) bool {
+	hasher, canHash := target.(itemHasher)
+	return func(file localFile, item boox.LibraryItem) bool {
+		if file.size <= 0 || item.Size != file.size {
+			return false
+		}
+		if !canHash {
+			return !item.UpdatedAt.IsZero() && !item.UpdatedAt.Before(file.modTime)
+		}
+		remote, err := hasher.hashItem(ctx, item.ID)
+		if err != nil {
+			return false
+		}
+		local, err := hashFile(file.path)
+		return err == nil && local == remote
+	}
+}
+
+func hashFile(name string) (string, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func planFolder(plan *SyncPlan, folder []string, files []localFile, items []boox.LibraryItem, sameContent func(localFile, boox.LibraryItem) bool, options SyncOptions) {
+	remote := []boox.LibraryItem{}
+	for _, item := range items {
+		if !item.IsFolder {
+			remote = append(remote, item)
+		}
+	}
+	matched := make([]bool, len(remote))
+	pending := []localFile{}
+
+	match := func(file localFile, same func(boox.LibraryItem) bool) bool {
+		for index, item := range remote {
+			if matched[index] || !same(item) {
+				continue
+			}
+			matched[index] = true
+			if item.Size > 0 && file.size > 0 && item.Size != file.size {
+				plan.Actions = append(plan.Actions, SyncAction{Kind: SyncReplace, Path: devicePath(folder, file.name), Size: file.size, folder: folder, localPath: file.path, itemID: item.ID})
+			} else {
+				plan.Unchanged++
+			}
+			return true
+		}
+		return false
+	}
+
+	for _, file := range files {
+		if !match(file, func(item boox.LibraryItem) bool { return strings.EqualFold(item.Title, file.name) }) {
+			pending = append(pending, file)
+		}
+	}
+
+	uploads := []localFile{}
+	for _, file := range pending {
+		stem := strings.TrimSuffix(file.name, filepath.Ext(file.name))
+		if !match(file, func(item boox.LibraryItem) bool {
+			return filepath.Ext(item.Title) == "" && strings.EqualFold(item.Title, stem)
+		}) {
+			uploads = append(uploads, file)
+		}
+	}
+
+	localSizes, remoteSizes := map[int64]int{}, map[int64]int{}
+	for _, file := range uploads {
+		localSizes[file.size]++
+	}
+	for index, item := range remote {
+		if !matched[index] {
+			remoteSizes[item.Size]++
+		}
+	}
+
+	for _, file := range uploads {
+		renamed := false
+		for index, item := range remote {
+			if matched[index] || item.Size != file.size {
+				continue
+			}
+			if localSizes[file.size] == 1 && remoteSizes[item.Size] == 1 && sameContent(file, item) {
+				matched[index] = true
+				renamed = true
+				plan.Actions = append(plan.Actions, SyncAction{Kind: SyncRename, Path: devicePath(folder, file.name), From: devicePath(folder, item.Title), Size: file.size, folder: folder, itemID: item.ID})
+			}
+			break
+		}
+		if !renamed {
+			plan.Actions = append(plan.Actions, SyncAction{Kind: SyncUpload, Path: devicePath(folder, file.name), Size: file.size, folder: folder, localPath: file.path})
+		}
+	}
+
+	if options.Delete {
+		for index, item := range remote {
+			if !matched[index] {
+				plan.Actions = append(plan.Actions, SyncAction{Kind: SyncDelete, Path: devicePath(folder, item.Title), Size: item.Size, folder: folder, itemID: item.ID})
+			}
+		}
+	}
+}
+
+func ApplySync(ctx context.Context, target SyncTarget, plan SyncPlan, updates chan<- ProgressUpdate) error {
+	tracker := newProgressTracker(updates, len(plan.Actions))
+	resolver := newFolderResolver(target, true)
+
+	for index, action := range plan.Actions {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("sync cancelled: %w", err)
+		}
+		prefix := fmt.Sprintf("Action %d/%d: ", index+1, len(plan.Actions))
+
+		switch action.Kind {
+		case SyncRename:
+			tracker.message(prefix + "Renaming " + action.From)
+			if err := target.RenameItem(ctx, action.itemID, path.Base(action.Path)); err != nil {
+				return fmt.Errorf("error renaming %s: %w", action.From, err)
+			}
+		case SyncDelete:
+			tracker.message(prefix + "Deleting " + action.Path)
+			if err := target.DeleteItem(ctx, action.itemID); err != nil {
+				return fmt.Errorf("error deleting %s: %w", action.Path, err)
+			}
+		case SyncUpload, SyncReplace:
+			if err := uploadSyncFile(ctx, target, resolver, action, tracker.uploadProgress(prefix+"Transferring "+action.Path)); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unknown sync action %q", action.Kind)
+		}
+		tracker.advance(prefix + "Done " + action.String())
+	}
+	return nil
+}
+
+func uploadSyncFile(ctx context.Context, target SyncTarget, resolver *folderResolver, action SyncAction, progress boox.UploadProgress) error {
+	folderID := ""
+	if len(action.folder) > 0 {
+		var err error
+		folderID, err = resolver.folder(ctx, action.folder)
+		if err != nil {
+			return err
+		}
+	}
+
+	file, err := os.Open(action.localPath)
+	if err != nil {
+		return fmt.Errorf("unable to open %s: %w", action.localPath, err)
+	}
+	defer file.Close()
+
+	if err := replaceReader(ctx, target, folderID, path.Base(action.Path), action.itemID, file, action.Size, progress); err != nil {
+		return fmt.Errorf("error uploading %s: %w", action.Path, err)
+	}
+	return nil
+}
+
+func localTree(root string) (map[string][]localFile, error) {
+	directories := map[string][]localFile{"": {}}
+	err := filepath.WalkDir(root, func(current string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if current == root {
+			return nil
+		}
+		if strings.HasPrefix(entry.Name(), ".") {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		relative, err := filepath.Rel(root, current)
+		if err != nil {
+			return err
+		}
+		relative = filepath.ToSlash(relative)
+		if entry.IsDir() {
+			if _, ok := directories[relative]; !ok {
+				directories[relative] = []localFile{}
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		parent := path.Dir(relative)
+		if parent == "." {
+			parent = ""
+		}
+		directories[parent] = append(directories[parent], localFile{name: entry.Name(), path: current, size: info.Size(), modTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %w", root, err)
+	}
+	return directories, nil
+}
+
+func splitFolder(value string) []string {
+	segments := []string{}
+	for _, segment := range strings.Split(value, "/") {
+		if segment = strings.TrimSpace(segment); segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
+
+func devicePath(folder []string, name string) string {
+	return path.Join(append(append([]string{}, folder...), name)...)
+}
@@ -0,0 +1,193 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ssh-vom/boox-serve/internal/boox"
+)
+
+func writeTestFile(t *testing.T, root, name, content string) {
+	t.Helper()
+	target := filepath.Join(root, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(target, []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+}
+
+func TestPlanAndApplySync(t *testing.T) {
+	ctx := context.Background()
+	localDir, deviceDir := t.TempDir(), t.TempDir()
+
+	writeTestFile(t, localDir, "Same.pdf", "same")
+	writeTestFile(t, localDir, "Changed.epub", "new contents")
+	writeTestFile(t, localDir, "Renamed Title.pdf", "renamed")
+	writeTestFile(t, localDir, "Comic.cbz", "comic book")
+	writeTestFile(t, localDir, "Fiction/Novel.epub", "novel")
+	writeTestFile(t, localDir, ".hidden/Skip.pdf", "hidden")
+
+	writeTestFile(t, deviceDir, "Books/Same.pdf", "same")
+	writeTestFile(t, deviceDir, "Books/Changed.epub", "old")
+	writeTestFile(t, deviceDir, "Books/old_name.pdf", "renamed")
+	writeTestFile(t, deviceDir, "Books/Comic.zip", "comic book")
+	writeTestFile(t, deviceDir, "Books/Extra.pdf", "extra")
+
+	device := NewLocalSink(deviceDir)
+	plan, err := PlanSync(ctx, device, localDir, SyncOptions{Folder: "Books", Delete: true})
+	if err != nil {
+		t.Fatalf("PlanSync: %v", err)
+	}
+
+	got := map[string]SyncAction{}
+	for _, action := range plan.Actions {
+		got[action.String()] = action
+	}
+	want := []string{
+		"replace Books/Changed.epub",
+		"rename Books/Comic.zip -> Books/Comic.cbz",
+		"rename Books/old_name.pdf -> Books/Renamed Title.pdf",
+		"delete Books/Extra.pdf",
+		"upload Books/Fiction/Novel.epub",
+	}
+	if len(plan.Actions) != len(want) {
+		t.Fatalf("unexpected plan: %v", plan.Actions)
+	}
+	for _, action := range want {
+		if _, ok := got[action]; !ok {
+			t.Fatalf("missing %q in plan %v", action, plan.Actions)
+		}
+	}
+	if plan.Unchanged != 1 {
+		t.Fatalf("unchanged = %d, want 1", plan.Unchanged)
+	}
+
+	if err := ApplySync(ctx, device, plan, nil); err != nil {
+		t.Fatalf("ApplySync: %v", err)
+	}
+
+	for name, content := range map[string]string{
+		"Books/Same.pdf":           "same",
+		"Books/Changed.epub":       "new contents",
+		"Books/Renamed Title.pdf":  "renamed",
+		"Books/Comic.cbz":          "comic book",
+		"Books/Fiction/Novel.epub": "novel",
+	} {
+		data, err := os.ReadFile(filepath.Join(deviceDir, filepath.FromSlash(name)))
+		if err != nil || string(data) != content {
+			t.Fatalf("%s = %q, %v; want %q", name, data, err, content)
+		}
+	}
+	for _, name := range []string{"Books/Extra.pdf", "Books/old_name.pdf", "Books/Comic.zip", "Books/.hidden"} {
+		if _, err := os.Stat(filepath.Join(deviceDir, filepath.FromSlash(name))); !os.IsNotExist(err) {
+			t.Fatalf("expected %s to be gone, got %v", name, err)
+		}
+	}
+
+	plan, err = PlanSync(ctx, device, localDir, SyncOptions{Folder: "Books", Delete: true})
+	if err != nil {
+		t.Fatalf("second PlanSync: %v", err)
+	}
+	if len(plan.Actions) != 0 || plan.Unchanged != 5 {
+		t.Fatalf("expected device in sync, got %+v", plan)
+	}
+}
+
+func TestPlanSyncKeepsExtraFilesWithoutDelete(t *testing.T) {
+	localDir, deviceDir := t.TempDir(), t.TempDir()
+	writeTestFile(t, localDir, "Book.pdf", "book")
+	writeTestFile(t, deviceDir, "Other.epub", "other")
+
+	plan, err := PlanSync(context.Background(), NewLocalSink(deviceDir), localDir, SyncOptions{})
+	if err != nil {
+		t.Fatalf("PlanSync: %v", err)
+	}
+	if len(plan.Actions) != 1 || plan.Actions[0].String() != "upload Book.pdf" {
+		t.Fatalf("unexpected plan: %v", plan.Actions)
+	}
+}
+
+func TestApplySyncKeepsReplacedFileWhenUploadFails(t *testing.T) {
+	ctx := context.Background()
+	localDir := t.TempDir()
+	writeTestFile(t, localDir, "Book.pdf", "book")
+	device, client := newFakeDevice(t, boox.LibraryBook{IDString: "book-1", Name: "Book.pdf", Size: 99})
+	device.failUploads = true
+
+	plan, err := PlanSync(ctx, client, localDir, SyncOptions{Folder: "Series"})
+	if err != nil {
+		t.Fatalf("PlanSync: %v", err)
+	}
+	if len(plan.Actions) != 1 || plan.Actions[0].String() != "replace Series/Book.pdf" {
+		t.Fatalf("unexpected plan: %v", plan.Actions)
+	}
+	if err := ApplySync(ctx, client, plan, nil); err == nil {
+		t.Fatalf("expected upload error")
+	}
+	if len(device.deleted) != 0 {
+		t.Fatalf("expected old file to be kept, deleted %v", device.deleted)
+	}
+}
+
+func TestPlanSyncRenamesOnlyUnambiguousMatches(t *testing.T) {
+	localDir, deviceDir := t.TempDir(), t.TempDir()
+	writeTestFile(t, localDir, "New Title.pdf", "renamed")
+	writeTestFile(t, localDir, "Different.pdf", "abcd")
+	writeTestFile(t, localDir, "Twin.epub", "twin!")
+	writeTestFile(t, deviceDir, "Old Title.pdf", "renamed")
+	writeTestFile(t, deviceDir, "Unrelated.pdf", "wxyz")
+	writeTestFile(t, deviceDir, "Twin A.epub", "twin!")
+	writeTestFile(t, deviceDir, "Twin B.epub", "twin!")
+
+	plan, err := PlanSync(context.Background(), NewLocalSink(deviceDir), localDir, SyncOptions{})
+	if err != nil {
+		t.Fatalf("PlanSync: %v", err)
+	}
+	got := map[string]bool{}
+	for _, action := range plan.Actions {
+		got[action.String()] = true
+	}
+	want := []string{
+		"rename Old Title.pdf -> New Title.pdf",
+		"upload Different.pdf",
+		"upload Twin.epub",
+	}
+	if len(plan.Actions) != len(want) {
+		t.Fatalf("unexpected plan: %v", plan.Actions)
+	}
+	for _, action := range want {
+		if !got[action] {
+			t.Fatalf("missing %q in plan %v", action, plan.Actions)
+		}
+	}
+}
+
+func TestPlanSyncComparesUploadTimeWithoutHashes(t *testing.T) {
+	localDir := t.TempDir()
+	writeTestFile(t, localDir, "New Title.pdf", "renamed")
+	writeTestFile(t, localDir, "Edited.pdf", "edited")
+	for name, age := range map[string]time.Duration{"New Title.pdf": 2 * time.Hour, "Edited.pdf": 0} {
+		modTime := time.Now().Add(-age)
+		if err := os.Chtimes(filepath.Join(localDir, name), modTime, modTime); err != nil {
+			t.Fatalf("chtimes: %v", err)
+		}
+	}
+	uploaded := time.Now().Add(-time.Hour).UnixMilli()
+	_, client := newFakeDevice(t,
+		boox.LibraryBook{IDString: "book-1", Name: "Old Title.pdf", Size: 7, UpdatedAt: uploaded},
+		boox.LibraryBook{IDString: "book-2", Name: "Before Edit.pdf", Size: 6, UpdatedAt: uploaded},
+	)
+
+	plan, err := PlanSync(context.Background(), client, localDir, SyncOptions{Folder: "Series"})
+	if err != nil {
+		t.Fatalf("PlanSync: %v", err)
+	}
+	if len(plan.Actions) != 2 || plan.Actions[0].String() != "upload Series/Edited.pdf" || plan.Actions[1].String() != "rename Series/Old Title.pdf -> Series/New Title.pdf" {
+		t.Fatalf("expected only the file unchanged since upload to be renamed, got %v", plan.Actions)
+	}
+}
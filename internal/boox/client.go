@@ -4,14 +4,24 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 )
 
+const (
+	folderLookupPageSize = 200
+	uploadMinBytesPerSec = 256 << 10
+	uploadUnknownTimeout = 30 * time.Minute
+)
+
+var ErrFolderNotFound = errors.New("folder not found")
+
 type DeviceDetails struct {
 	Host         string `json:"host"`
 	ID           string `json:"id"`
@@ -30,11 +40,31 @@ type LibraryResponse struct {
 }
 
 type LibraryBook struct {
-	Title string `json:"title"`
+	IDString  string `json:"idString"`
+	Title     string `json:"title"`
+	Name      string `json:"name"`
+	Size      int64  `json:"size"`
+	CreatedAt int64  `json:"createdAt"`
+	UpdatedAt int64  `json:"updatedAt"`
 }
 
 type Library struct {
-	Title string `json:"title"`
+	IDString  string `json:"idString"`
+	Title     string `json:"title"`
+	Name      string `json:"name"`
+	BookCount int    `json:"bookCount"`
+	CreatedAt int64  `json:"createdAt"`
+	UpdatedAt int64  `json:"updatedAt"`
+}
+
+type LibraryItem struct {
+	ID        string
+	Title     string
+	IsFolder  bool
+	Size      int64
+	BookCount int
+	CreatedAt time.Time
+	UpdatedAt time.Time
 }
 
 type LibraryQueryParams struct {
@@ -94,6 +124,21 @@ func (client *Client) CheckConnection(ctx context.Context) (*DeviceDetails, erro
 }
 
 func (client *Client) GetLibraryTitles(ctx context.Context, params LibraryQueryParams) ([]string, error) {
+	libraryResp, err := client.GetLibrary(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	items := libraryResp.Items()
+	titles := make([]string, 0, len(items))
+	for _, item := range items {
+		titles = append(titles, item.Title)
+	}
+
+	return titles, nil
+}
+
+func (client *Client) GetLibrary(ctx context.Context, params LibraryQueryParams) (*LibraryResponse, error) {
 	queryURL, err := client.constructLibraryURL(params)
 	if err != nil {
 		return nil, fmt.Errorf("error constructing URL: %w", err)
@@ -120,16 +165,33 @@ func (client *Client) GetLibraryTitles(ctx context.Context, params LibraryQueryP
 		return nil, fmt.Errorf("error unmarshaling JSON: %w", err)
 	}
 
-	titles := make([]string, 0, len(libraryResp.VisibleBookList)+len(libraryResp.VisibleLibraryList))
-	for _, book := range libraryResp.VisibleBookList {
-		titles = append(titles, book.Title)
-	}
+	return &libraryResp, nil
+}
 
+func (libraryResp *LibraryResponse) Items() []LibraryItem {
+	items := make([]LibraryItem, 0, len(libraryResp.VisibleLibraryList)+len(libraryResp.VisibleBookList))
 	for _, library := range libraryResp.VisibleLibraryList {
-		titles = append(titles, library.Title)
+		items = append(items, LibraryItem{
+			ID:        library.IDString,
+			Title:     displayName(library.Title, library.Name),
+			IsFolder:  true,
+			BookCount: library.BookCount,
+			CreatedAt: fromMillis(library.CreatedAt),
+			UpdatedAt: fromMillis(library.UpdatedAt),
+		})
 	}
 
-	return titles, nil
+	for _, book := range libraryResp.VisibleBookList {
+		items = append(items, LibraryItem{
+			ID:        book.IDString,
+			Title:     displayName(book.Title, book.Name),
+			Size:      book.Size,
+			CreatedAt: fromMillis(book.CreatedAt),
+			UpdatedAt: fromMillis(book.UpdatedAt),
+		})
+	}
+
+	return items
 }
 
 func (client *Client) CreateFolder(ctx context.Context, parentID *string, title string) (string, error) {
@@ -172,61 +234,240 @@ func (client *Client) CreateFolder(ctx context.Context, parentID *string, title
 	return folderResponse.ID, nil
 }
 
+func (client *Client) FindFolder(ctx context.Context, parentID, title string) (string, error) {
+	title = strings.TrimSpace(title)
+	for offset := 0; ; {
+		libraryResp, err := client.GetLibrary(ctx, LibraryQueryParams{
+			Limit:           folderLookupPageSize,
+			Offset:          offset,
+			SortBy:          "title",
+			Order:           "asc",
+			LibraryUniqueID: parentID,
+		})
+		if err != nil {
+			return "", err
+		}
+
+		for _, library := range libraryResp.VisibleLibraryList {
+			name := strings.TrimSpace(displayName(library.Title, library.Name))
+			if library.IDString != "" && strings.EqualFold(name, title) {
+				return library.IDString, nil
+			}
+		}
+
+		pageSize := len(libraryResp.VisibleLibraryList) + len(libraryResp.VisibleBookList)
+		offset += pageSize
+		if pageSize == 0 || offset >= libraryResp.BookCount+libraryResp.LibraryCount {
+			return "", fmt.Errorf("%w: %s", ErrFolderNotFound, title)
+		}
+	}
+}
+
+func (client *Client) ListFolder(ctx context.Context, folderID string) ([]LibraryItem, error) {
+	items := []LibraryItem{}
+	for offset := 0; ; {
+		libraryResp, err := client.GetLibrary(ctx, LibraryQueryParams{
+			Limit:           folderLookupPageSize,
+			Offset:          offset,
+			SortBy:          "title",
+			Order:           "asc",
+			LibraryUniqueID: folderID,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		page := libraryResp.Items()
+		items = append(items, page...)
+		offset += len(page)
+		if len(page) == 0 || offset >= libraryResp.BookCount+libraryResp.LibraryCount {
+			return items, nil
+		}
+	}
+}
+
+type UploadProgress func(sent, total int64)
+
 func (client *Client) UploadFile(ctx context.Context, parentID, fileName string, fileData []byte) error {
+	return client.UploadReader(ctx, parentID, fileName, bytes.NewReader(fileData), int64(len(fileData)), nil)
+}
+
+func (client *Client) UploadReader(ctx context.Context, parentID, fileName string, reader io.Reader, size int64, progress UploadProgress) error {
 	endpoint := client.baseURL + "/api/library/upload"
 
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
+	boundary := multipart.NewWriter(io.Discard).Boundary()
+	contentLength := int64(-1)
+	if size >= 0 {
+		overhead, err := multipartOverhead(boundary, parentID, fileName)
+		if err != nil {
+			return err
+		}
+		contentLength = overhead + size
+	}
+
+	pipeReader, pipeWriter := io.Pipe()
+	writer := multipart.NewWriter(pipeWriter)
+	if err := writer.SetBoundary(boundary); err != nil {
+		return fmt.Errorf("unable to set form boundary: %w", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		pipeWriter.CloseWithError(writeUploadForm(writer, parentID, fileName, &progressReader{reader: reader, total: size, progress: progress}))
+	}()
+	defer func() {
+		pipeReader.Close()
+		<-done
+	}()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, pipeReader)
+	if err != nil {
+		return fmt.Errorf("unable to create upload request: %w", err)
+	}
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+	request.ContentLength = contentLength
+
+	uploadClient := *client.httpClient
+	uploadClient.Timeout = uploadTimeout(client.httpClient.Timeout, size)
+	response, err := uploadClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("upload request failed: %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(response.Body)
+		return fmt.Errorf("upload failed: %d %s", response.StatusCode, string(body))
+	}
+
+	return nil
+}
 
+func uploadTimeout(base time.Duration, size int64) time.Duration {
+	if base <= 0 {
+		return 0
+	}
+	if size < 0 {
+		return max(base, uploadUnknownTimeout)
+	}
+	return base + time.Duration(size/uploadMinBytesPerSec)*time.Second
+}
+
+func writeUploadForm(writer *multipart.Writer, parentID, fileName string, reader io.Reader) error {
 	part, err := writer.CreateFormFile("file", fileName)
 	if err != nil {
 		return fmt.Errorf("unable to create form file: %w", err)
 	}
 
-	if _, err := part.Write(fileData); err != nil {
-		return fmt.Errorf("unable to write file data: %w", err)
+	if reader != nil {
+		if _, err := io.Copy(part, reader); err != nil {
+			return fmt.Errorf("unable to write file data: %w", err)
+		}
 	}
 
 	if parentID != "" {
-		writer.WriteField("parent", parentID)
+		if err := writer.WriteField("parent", parentID); err != nil {
+			return fmt.Errorf("unable to write parent field: %w", err)
+		}
+	}
+	if err := writer.WriteField("name", fileName); err != nil {
+		return fmt.Errorf("unable to write name field: %w", err)
 	}
-	writer.WriteField("name", fileName)
 
 	if err := writer.Close(); err != nil {
 		return fmt.Errorf("unable to finalize form: %w", err)
 	}
 
-	request, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, body)
+	return nil
+}
+
+func multipartOverhead(boundary, parentID, fileName string) (int64, error) {
+	counter := &countingWriter{}
+	writer := multipart.NewWriter(counter)
+	if err := writer.SetBoundary(boundary); err != nil {
+		return 0, fmt.Errorf("unable to set form boundary: %w", err)
+	}
+	if err := writeUploadForm(writer, parentID, fileName, nil); err != nil {
+		return 0, err
+	}
+	return counter.count, nil
+}
+
+type countingWriter struct {
+	count int64
+}
+
+func (writer *countingWriter) Write(data []byte) (int, error) {
+	writer.count += int64(len(data))
+	return len(data), nil
+}
+
+type progressReader struct {
+	reader   io.Reader
+	total    int64
+	sent     int64
+	progress UploadProgress
+}
+
+func (reader *progressReader) Read(data []byte) (int, error) {
+	n, err := reader.reader.Read(data)
+	if n > 0 {
+		reader.sent += int64(n)
+		if reader.progress != nil {
+			reader.progress(reader.sent, reader.total)
+		}
+	}
+	return n, err
+}
+
+func (client *Client) RenameItem(ctx context.Context, idString, newName string) error {
+	endpoint := client.baseURL + "/api/library/rename"
+
+	payload := struct {
+		IDString string `json:"idString"`
+		File     string `json:"file"`
+		Name     string `json:"name"`
+	}{
+		IDString: idString,
+		File:     idString,
+		Name:     newName,
+	}
+
+	jsonPayload, err := json.Marshal(payload)
 	if err != nil {
-		return fmt.Errorf("unable to create upload request: %w", err)
+		return fmt.Errorf("error marshaling JSON: %w", err)
 	}
-	request.Header.Set("Content-Type", writer.FormDataContentType())
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewBuffer(jsonPayload))
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
 
 	response, err := client.httpClient.Do(request)
 	if err != nil {
-		return fmt.Errorf("upload request failed: %w", err)
+		return fmt.Errorf("error sending request: %w", err)
 	}
 	defer response.Body.Close()
 
 	if response.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(response.Body)
-		return fmt.Errorf("upload failed: %d %s", response.StatusCode, string(body))
+		return fmt.Errorf("unexpected status code: %d, body: %s", response.StatusCode, string(body))
 	}
 
 	return nil
 }
 
-func (client *Client) RenameItem(ctx context.Context, idString, newName string) error {
-	endpoint := client.baseURL + "/api/library/rename"
+func (client *Client) DeleteItem(ctx context.Context, idString string) error {
+	endpoint := client.baseURL + "/api/library/delete"
 
 	payload := struct {
 		IDString string `json:"idString"`
 		File     string `json:"file"`
-		Name     string `json:"name"`
 	}{
 		IDString: idString,
 		File:     idString,
-		Name:     newName,
 	}
 
 	jsonPayload, err := json.Marshal(payload)
@@ -283,3 +524,17 @@ func (client *Client) constructLibraryURL(params LibraryQueryParams) (string, er
 
 	return u.String(), nil
 }
+
+func displayName(title, name string) string {
+	if title != "" {
+		return title
+	}
+	return name
+}
+
+func fromMillis(value int64) time.Time {
+	if value <= 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(value)
+}
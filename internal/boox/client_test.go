@@ -0,0 +1,233 @@
+package boox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestUploadReaderStreamsMultipartForm(t *testing.T) {
+	content := strings.Repeat("page-data", 4096)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/library/upload" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.ContentLength <= int64(len(content)) {
+			t.Errorf("expected content length to include form overhead, got %d", r.ContentLength)
+		}
+
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("unable to parse form: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if got := r.FormValue("parent"); got != "folder-1" {
+			t.Errorf("unexpected parent %q", got)
+		}
+		if got := r.FormValue("name"); got != "Chapter 1.cbz" {
+			t.Errorf("unexpected name %q", got)
+		}
+
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			t.Errorf("missing file part: %v", err)
+			return
+		}
+		defer file.Close()
+		data, _ := io.ReadAll(file)
+		if header.Filename != "Chapter 1.cbz" || string(data) != content {
+			t.Errorf("unexpected file part %q (%d bytes)", header.Filename, len(data))
+		}
+	}))
+	defer server.Close()
+
+	var lastSent, lastTotal int64
+	client := NewClient(server.URL, server.Client())
+	err := client.UploadReader(context.Background(), "folder-1", "Chapter 1.cbz", strings.NewReader(content), int64(len(content)), func(sent, total int64) {
+		lastSent, lastTotal = sent, total
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if lastSent != int64(len(content)) || lastTotal != int64(len(content)) {
+		t.Fatalf("unexpected final progress %d/%d", lastSent, lastTotal)
+	}
+}
+
+func TestUploadReaderUnknownSize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("unable to parse form: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, server.Client())
+	if err := client.UploadReader(context.Background(), "", "book.epub", strings.NewReader("epub"), -1, nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestFindFolderWalksPages(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var args struct {
+			Offset          int    `json:"offset"`
+			LibraryUniqueID string `json:"libraryUniqueId"`
+		}
+		if err := json.Unmarshal([]byte(r.URL.Query().Get("args")), &args); err != nil {
+			t.Errorf("unable to parse args: %v", err)
+		}
+
+		response := LibraryResponse{LibraryCount: 2, BookCount: 1}
+		switch args.Offset {
+		case 0:
+			response.VisibleLibraryList = []Library{{IDString: "folder-1", Name: "Berserk"}}
+		case 1:
+			response.VisibleLibraryList = []Library{{IDString: "folder-2", Title: "One Piece"}}
+		case 2:
+			response.VisibleBookList = []LibraryBook{{IDString: "book-1", Name: "notes.pdf"}}
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, server.Client())
+	folderID, err := client.FindFolder(context.Background(), "", "one piece")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if folderID != "folder-2" {
+		t.Fatalf("expected folder-2, got %q", folderID)
+	}
+
+	if _, err := client.FindFolder(context.Background(), "", "Naruto"); !errors.Is(err, ErrFolderNotFound) {
+		t.Fatalf("expected ErrFolderNotFound, got %v", err)
+	}
+}
+
+func TestUploadReaderStopsReportingProgressOnFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "disk full", http.StatusInsufficientStorage)
+	}))
+	defer server.Close()
+
+	var returned atomic.Bool
+	content := strings.Repeat("x", 8<<20)
+	client := NewClient(server.URL, server.Client())
+	err := client.UploadReader(context.Background(), "", "big.cbz", strings.NewReader(content), int64(len(content)), func(sent, total int64) {
+		if returned.Load() {
+			t.Errorf("progress reported after upload returned (%d/%d)", sent, total)
+		}
+	})
+	returned.Store(true)
+	if err == nil {
+		t.Fatalf("expected upload error")
+	}
+	time.Sleep(50 * time.Millisecond)
+}
+
+func TestUploadTimeoutScalesWithSize(t *testing.T) {
+	if got := uploadTimeout(0, 1<<30); got != 0 {
+		t.Fatalf("expected no timeout when the client has none, got %v", got)
+	}
+	if got := uploadTimeout(30*time.Second, 10<<20); got != 70*time.Second {
+		t.Fatalf("expected 70s for 10 MiB, got %v", got)
+	}
+	if got := uploadTimeout(30*time.Second, -1); got != uploadUnknownTimeout {
+		t.Fatalf("expected %v for unknown size, got %v", uploadUnknownTimeout, got)
+	}
+}
+
+func TestGetLibrarySendsArgsAndMapsItems(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/api/library" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		var args map[string]interface{}
+		if err := json.Unmarshal([]byte(r.URL.Query().Get("args")), &args); err != nil {
+			t.Errorf("unable to parse args: %v", err)
+		}
+		if args["limit"] != float64(50) || args["offset"] != float64(10) || args["sortBy"] != "updatedAt" || args["order"] != "desc" || args["libraryUniqueId"] != "folder-1" {
+			t.Errorf("unexpected args %v", args)
+		}
+		w.Write([]byte(`{"bookCount":1,"libraryCount":1,
+			"visibleLibraryList":[{"idString":"folder-2","name":"Volume 1","bookCount":3,"createdAt":1700000000000}],
+			"visibleBookList":[{"idString":"book-1","title":"","name":"Chapter 1.cbz","size":2048,"updatedAt":1700000001000}]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, server.Client())
+	response, err := client.GetLibrary(context.Background(), LibraryQueryParams{Limit: 50, Offset: 10, SortBy: "updatedAt", Order: "desc", LibraryUniqueID: "folder-1"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	items := response.Items()
+	if len(items) != 2 {
+		t.Fatalf("expected 2 items, got %+v", items)
+	}
+	folder, book := items[0], items[1]
+	if folder.ID != "folder-2" || folder.Title != "Volume 1" || !folder.IsFolder || folder.BookCount != 3 || folder.CreatedAt.UnixMilli() != 1700000000000 {
+		t.Fatalf("unexpected folder %+v", folder)
+	}
+	if book.ID != "book-1" || book.Title != "Chapter 1.cbz" || book.IsFolder || book.Size != 2048 || book.UpdatedAt.UnixMilli() != 1700000001000 || !book.CreatedAt.IsZero() {
+		t.Fatalf("unexpected book %+v", book)
+	}
+}
+
+func TestGetLibraryReportsStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "busy", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, server.Client())
+	if _, err := client.GetLibrary(context.Background(), LibraryQueryParams{}); err == nil || !strings.Contains(err.Error(), "503") {
+		t.Fatalf("expected status error, got %v", err)
+	}
+}
+
+func TestDeleteItemPostsID(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/library/delete" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("Content-Type"); got != "application/json" {
+			t.Errorf("unexpected content type %q", got)
+		}
+		var payload map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("unable to parse payload: %v", err)
+		}
+		if len(payload) != 2 || payload["idString"] != "book-1" || payload["file"] != "book-1" {
+			t.Errorf("unexpected payload %v", payload)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, server.Client())
+	if err := client.DeleteItem(context.Background(), "book-1"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestDeleteItemReportsStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no such item", http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, server.Client())
+	err := client.DeleteItem(context.Background(), "missing")
+	if err == nil || !strings.Contains(err.Error(), "404") || !strings.Contains(err.Error(), "no such item") {
+		t.Fatalf("expected status error, got %v", err)
+	}
+}
@@ -11,6 +11,9 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+
+	"github.com/ssh-vom/boox-serve/internal/imaging"
+	"github.com/ssh-vom/boox-serve/internal/providers/manga"
 )
 
 const (
@@ -18,18 +21,40 @@ const (
 	configDirName   = "boox-serve"
 	configFileName  = "config.json"
 	envFileName     = ".env"
+	queueFileName   = "queue.json"
 )
 
 type ProviderConfig struct {
-	MangaDexAPIKey string `json:"mangadex_api_key,omitempty"`
+	MangaDexAPIKey   string           `json:"mangadex_api_key,omitempty"`
+	LibGenMirror     string           `json:"libgen_mirror,omitempty"`
+	ChapterLanguages []string         `json:"chapter_languages,omitempty"`
+	ContentRatings   []string         `json:"content_ratings,omitempty"`
+	Dedupe           manga.DedupeMode `json:"dedupe,omitempty"`
+}
+
+type OutputConfig struct {
+	Target   string `json:"target,omitempty"`
+	LocalDir string `json:"local_dir,omitempty"`
+}
+
+type SeriesConfig struct {
+	Title    string             `json:"title,omitempty"`
+	Spreads  imaging.SpreadMode `json:"spreads,omitempty"`
+	Crop     *bool              `json:"crop,omitempty"`
+	Groups   []string           `json:"groups,omitempty"`
+	Followed bool               `json:"followed,omitempty"`
 }
 
 type Config struct {
-	BooxURL   string         `json:"boox_url"`
-	BooxIP    string         `json:"boox_ip"`
-	BooxPort  int            `json:"boox_port"`
-	Verbose   bool           `json:"verbose"`
-	Providers ProviderConfig `json:"providers,omitempty"`
+	BooxURL     string                  `json:"boox_url"`
+	BooxIP      string                  `json:"boox_ip"`
+	BooxPort    int                     `json:"boox_port"`
+	Verbose     bool                    `json:"verbose"`
+	MangaFolder string                  `json:"manga_folder,omitempty"`
+	Images      imaging.Options         `json:"images"`
+	Output      OutputConfig            `json:"output"`
+	Series      map[string]SeriesConfig `json:"series,omitempty"`
+	Providers   ProviderConfig          `json:"providers,omitempty"`
 }
 
 func DefaultConfig() Config {
@@ -38,6 +63,21 @@ func DefaultConfig() Config {
 	}
 }
 
+func (cfg Config) ImagesForSeries(mangaID string) imaging.Options {
+	options := cfg.Images
+	if series, ok := cfg.Series[mangaID]; ok && series.Spreads != "" {
+		options.Spreads = series.Spreads
+	}
+	if series, ok := cfg.Series[mangaID]; ok && series.Crop != nil {
+		options.Crop = *series.Crop
+	}
+	return options
+}
+
+func (cfg Config) ChaptersForSeries(mangaID string, chapters []manga.Chapter) []manga.Chapter {
+	return manga.DedupeChapters(chapters, cfg.Providers.Dedupe, cfg.Series[mangaID].Groups)
+}
+
 func ConfigDir() (string, error) {
 	configDir, err := os.UserConfigDir()
 	if err != nil {
@@ -65,6 +105,15 @@ func EnvPath() (string, error) {
 	return filepath.Join(configDir, envFileName), nil
 }
 
+func QueuePath() (string, error) {
+	configDir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(configDir, queueFileName), nil
+}
+
 func LoadEnv() error {
 	envPath, err := EnvPath()
 	if err != nil {
@@ -180,11 +229,41 @@ func ApplyEnvDefaults(cfg Config) Config {
 			cfg.Verbose = value == "1" || strings.EqualFold(value, "true")
 		}
 	}
+	if cfg.MangaFolder == "" {
+		if value := strings.TrimSpace(os.Getenv("BOOX_MANGA_FOLDER")); value != "" {
+			cfg.MangaFolder = value
+		}
+	}
+	if cfg.Output.Target == "" {
+		if value := strings.TrimSpace(os.Getenv("BOOX_OUTPUT_TARGET")); value != "" {
+			cfg.Output.Target = value
+		}
+	}
+	if cfg.Output.LocalDir == "" {
+		if value := strings.TrimSpace(os.Getenv("BOOX_OUTPUT_DIR")); value != "" {
+			cfg.Output.LocalDir = value
+		}
+	}
+	if !cfg.Images.Transcode {
+		if value := strings.TrimSpace(os.Getenv("BOOX_TRANSCODE_IMAGES")); value != "" {
+			cfg.Images.Transcode = value == "1" || strings.EqualFold(value, "true")
+		}
+	}
 	if cfg.Providers.MangaDexAPIKey == "" {
 		if value := strings.TrimSpace(os.Getenv("BOOX_MANGADEX_API_KEY")); value != "" {
 			cfg.Providers.MangaDexAPIKey = value
 		}
 	}
+	if len(cfg.Providers.ChapterLanguages) == 0 {
+		if value := strings.TrimSpace(os.Getenv("BOOX_CHAPTER_LANGUAGES")); value != "" {
+			cfg.Providers.ChapterLanguages = strings.Split(value, ",")
+		}
+	}
+	if cfg.Providers.LibGenMirror == "" {
+		if value := strings.TrimSpace(os.Getenv("BOOX_LIBGEN_MIRROR")); value != "" {
+			cfg.Providers.LibGenMirror = value
+		}
+	}
 
 	return cfg
 }
@@ -1,6 +1,13 @@
 package config
 
-import "testing"
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ssh-vom/boox-serve/internal/imaging"
+	"github.com/ssh-vom/boox-serve/internal/providers/manga"
+)
 
 func TestConfigBaseURLWithExplicitURL(t *testing.T) {
 	cfg := Config{BooxURL: "http://192.168.1.10:8085", BooxPort: 8085}
@@ -35,3 +42,50 @@ func TestConfigBaseURLRequiresValue(t *testing.T) {
 		t.Fatalf("expected error when no URL or IP is set")
 	}
 }
+
+func TestConfigImagesForSeries(t *testing.T) {
+	crop := false
+	cfg := Config{
+		Images: imaging.Options{Grayscale: true, Crop: true, Spreads: imaging.SpreadsRotate},
+		Series: map[string]SeriesConfig{"manga-1": {Spreads: imaging.SpreadsSplit, Crop: &crop}},
+	}
+
+	if options := cfg.ImagesForSeries("manga-1"); options.Spreads != imaging.SpreadsSplit || options.Crop || !options.Grayscale {
+		t.Fatalf("expected series override on top of global options, got %+v", options)
+	}
+	if options := cfg.ImagesForSeries("other"); options.Spreads != imaging.SpreadsRotate || !options.Crop {
+		t.Fatalf("expected global options, got %+v", options)
+	}
+}
+
+func TestConfigChaptersForSeriesDedupes(t *testing.T) {
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	chapters := []manga.Chapter{
+		{ID: "1a", Number: "1", Group: "Fast Scans", PublishedAt: day.AddDate(0, 0, 2), Pages: 20},
+		{ID: "1b", Number: "1", Group: "Quality Scans", PublishedAt: day, Pages: 18},
+		{ID: "2a", Number: "2", Group: "Fast Scans", PublishedAt: day, Pages: 20},
+		{ID: "2b", Number: "2", Group: "Other", PublishedAt: day.AddDate(0, 0, 1), Pages: 19},
+		{ID: "3a", Number: "3", Group: "Other", PublishedAt: day, Pages: 20},
+		{ID: "3b", Number: "3", Group: "Another", PublishedAt: day, Pages: 22},
+		{ID: "os1", Title: "Oneshot"},
+		{ID: "os2", Title: "Extra"},
+	}
+
+	cfg := Config{
+		Providers: ProviderConfig{Dedupe: manga.DedupeBest},
+		Series:    map[string]SeriesConfig{"manga-1": {Groups: []string{"quality scans"}}},
+	}
+
+	ids := []string{}
+	for _, chapter := range cfg.ChaptersForSeries("manga-1", chapters) {
+		ids = append(ids, chapter.ID)
+	}
+	if got := strings.Join(ids, ","); got != "1b,2b,3b,os1,os2" {
+		t.Fatalf("unexpected deduped chapters %s", got)
+	}
+
+	cfg.Providers.Dedupe = manga.DedupeAll
+	if got := cfg.ChaptersForSeries("manga-1", chapters); len(got) != len(chapters) {
+		t.Fatalf("expected every upload with dedupe off, got %d", len(got))
+	}
+}
@@ -0,0 +1,121 @@
+package imaging
+
+import (
+	"image"
+	"image/draw"
+)
+
+const (
+	cropLightLevel   = 230
+	cropDarkLevel    = 25
+	cropEdgeAgree    = 0.9
+	cropNoise        = 0.005
+	cropPadding      = 0.01
+	cropMaxSide      = 0.2
+	cropMinReduction = 0.01
+)
+
+func cropMargins(source image.Image) image.Image {
+	bounds := source.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width < 16 || height < 16 {
+		return source
+	}
+
+	gray, ok := source.(*image.Gray)
+	if !ok || gray.Bounds().Min != (image.Point{}) {
+		gray = image.NewGray(image.Rect(0, 0, width, height))
+		draw.Draw(gray, gray.Bounds(), source, bounds.Min, draw.Src)
+	}
+
+	isContent, ok := borderBackground(gray)
+	if !ok {
+		return source
+	}
+
+	rowContent := func(y, left, right int) bool {
+		count, limit := 0, int(float64(right-left)*cropNoise)
+		for x := left; x < right; x++ {
+			if isContent(gray.Pix[y*gray.Stride+x]) {
+				if count++; count > limit {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	columnContent := func(x, top, bottom int) bool {
+		count, limit := 0, int(float64(bottom-top)*cropNoise)
+		for y := top; y < bottom; y++ {
+			if isContent(gray.Pix[y*gray.Stride+x]) {
+				if count++; count > limit {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	top, bottom := 0, height
+	for top < height && !rowContent(top, 0, width) {
+		top++
+	}
+	if top == height {
+		return source
+	}
+	for bottom > top && !rowContent(bottom-1, 0, width) {
+		bottom--
+	}
+	left, right := 0, width
+	for left < width && !columnContent(left, top, bottom) {
+		left++
+	}
+	for right > left && !columnContent(right-1, top, bottom) {
+		right--
+	}
+
+	padX, padY := int(float64(width)*cropPadding), int(float64(height)*cropPadding)
+	maxX, maxY := int(float64(width)*cropMaxSide), int(float64(height)*cropMaxSide)
+	left = min(max(left-padX, 0), maxX)
+	top = min(max(top-padY, 0), maxY)
+	right = max(min(right+padX, width), width-maxX)
+	bottom = max(min(bottom+padY, height), height-maxY)
+
+	if float64((right-left)*(bottom-top)) > float64(width*height)*(1-cropMinReduction) {
+		return source
+	}
+	return subImage(source, image.Rect(left, top, right, bottom).Add(bounds.Min))
+}
+
+func borderBackground(gray *image.Gray) (func(uint8) bool, bool) {
+	bounds := gray.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	light, dark, total := 0, 0, 0
+	sample := func(x, y int) {
+		value := gray.Pix[y*gray.Stride+x]
+		if value >= cropLightLevel {
+			light++
+		} else if value <= cropDarkLevel {
+			dark++
+		}
+		total++
+	}
+	for x := 0; x < width; x++ {
+		sample(x, 0)
+		sample(x, height-1)
+	}
+	for y := 1; y < height-1; y++ {
+		sample(0, y)
+		sample(width-1, y)
+	}
+
+	switch {
+	case float64(light) >= float64(total)*cropEdgeAgree:
+		return func(value uint8) bool { return value < cropLightLevel }, true
+	case float64(dark) >= float64(total)*cropEdgeAgree:
+		return func(value uint8) bool { return value > cropDarkLevel }, true
+	default:
+		return nil, false
+	}
+}
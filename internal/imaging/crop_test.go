@@ -0,0 +1,77 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"testing"
+)
+
+func pageWithContent(width, height int, content image.Rectangle, background, ink uint8) *image.Gray {
+	page := image.NewGray(image.Rect(0, 0, width, height))
+	for index := range page.Pix {
+		page.Pix[index] = background
+	}
+	for y := content.Min.Y; y < content.Max.Y; y++ {
+		for x := content.Min.X; x < content.Max.X; x++ {
+			page.Pix[y*page.Stride+x] = ink
+		}
+	}
+	return page
+}
+
+func TestCropMarginsTrimsWhiteBorders(t *testing.T) {
+	page := pageWithContent(100, 200, image.Rect(10, 20, 90, 180), 255, 0)
+
+	cropped := cropMargins(page).Bounds()
+	if cropped != image.Rect(9, 18, 91, 182) {
+		t.Fatalf("expected content with padding, got %v", cropped)
+	}
+}
+
+func TestCropMarginsTrimsBlackBorders(t *testing.T) {
+	page := pageWithContent(100, 100, image.Rect(10, 10, 90, 90), 0, 200)
+
+	if cropped := cropMargins(page).Bounds(); cropped != image.Rect(9, 9, 91, 91) {
+		t.Fatalf("expected dark border to be trimmed, got %v", cropped)
+	}
+}
+
+func TestCropMarginsNeverCutsPastSafetyLimit(t *testing.T) {
+	page := pageWithContent(100, 100, image.Rect(45, 45, 55, 55), 255, 0)
+
+	if cropped := cropMargins(page).Bounds(); cropped != image.Rect(20, 20, 80, 80) {
+		t.Fatalf("expected crop to stop at 20%% per side, got %v", cropped)
+	}
+}
+
+func TestCropMarginsKeepsFullBleedArt(t *testing.T) {
+	page := pageWithContent(100, 100, image.Rect(0, 0, 100, 60), 255, 0)
+
+	if cropped := cropMargins(page); cropped != image.Image(page) {
+		t.Fatalf("expected full-bleed page to be left alone, got %v", cropped.Bounds())
+	}
+}
+
+func TestPrepareCropsBeforeSplittingSpreads(t *testing.T) {
+	spread := pageWithContent(240, 100, image.Rect(20, 10, 220, 90), 255, 0)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, spread); err != nil {
+		t.Fatalf("unable to encode spread: %v", err)
+	}
+
+	pages, err := Prepare(buf.Bytes(), Options{Crop: true, Spreads: SpreadsSplit})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(pages) != 2 {
+		t.Fatalf("expected two pages, got %d", len(pages))
+	}
+	decoded, err := png.Decode(bytes.NewReader(pages[0].Data))
+	if err != nil {
+		t.Fatalf("unable to decode page: %v", err)
+	}
+	if size := decoded.Bounds().Size(); size.X != 102 || size.Y != 82 {
+		t.Fatalf("expected cropped half page, got %v", size)
+	}
+}
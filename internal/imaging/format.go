@@ -0,0 +1,179 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+type Format string
+
+const (
+	JPEG Format = "jpeg"
+	PNG  Format = "png"
+	GIF  Format = "gif"
+	WebP Format = "webp"
+)
+
+var (
+	ErrUnknownFormat       = errors.New("unrecognised image format")
+	ErrCorruptImage        = errors.New("corrupt image")
+	ErrDecoderUnavailable  = errors.New("no decoder available for image format")
+	errWebPHeaderTruncated = errors.New("webp header truncated")
+)
+
+func Sniff(data []byte) (Format, error) {
+	switch http.DetectContentType(data) {
+	case "image/jpeg":
+		return JPEG, nil
+	case "image/png":
+		return PNG, nil
+	case "image/gif":
+		return GIF, nil
+	case "image/webp":
+		return WebP, nil
+	default:
+		return "", ErrUnknownFormat
+	}
+}
+
+func (format Format) Extension() string {
+	if format == JPEG {
+		return "jpg"
+	}
+	return string(format)
+}
+
+func (format Format) MIMEType() string {
+	return "image/" + string(format)
+}
+
+func (format Format) ReaderSupported() bool {
+	return format == JPEG || format == PNG
+}
+
+func Validate(data []byte) (Format, error) {
+	format, err := Sniff(data)
+	if err != nil {
+		return "", err
+	}
+
+	if _, _, err := image.Decode(bytes.NewReader(data)); err != nil {
+		if errors.Is(err, image.ErrFormat) && format == WebP {
+			if err := validateWebPContainer(data); err != nil {
+				return "", fmt.Errorf("%w: %v", ErrCorruptImage, err)
+			}
+			return format, nil
+		}
+		return "", fmt.Errorf("%w: %v", ErrCorruptImage, err)
+	}
+
+	return format, nil
+}
+
+func decode(data []byte) (image.Image, error) {
+	source, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		if errors.Is(err, image.ErrFormat) {
+			return nil, ErrDecoderUnavailable
+		}
+		return nil, fmt.Errorf("%w: %v", ErrCorruptImage, err)
+	}
+	return source, nil
+}
+
+func encode(source image.Image, format Format, options Options) (Page, error) {
+	var buf bytes.Buffer
+	if options.Optimize || format == JPEG {
+		if err := jpeg.Encode(&buf, source, &jpeg.Options{Quality: options.JPEGQuality()}); err != nil {
+			return Page{}, fmt.Errorf("error encoding jpeg: %w", err)
+		}
+		return Page{Data: buf.Bytes(), Format: JPEG}, nil
+	}
+
+	if err := png.Encode(&buf, source); err != nil {
+		return Page{}, fmt.Errorf("error encoding png: %w", err)
+	}
+	return Page{Data: buf.Bytes(), Format: PNG}, nil
+}
+
+func validateWebPContainer(data []byte) error {
+	if len(data) < 20 {
+		return errWebPHeaderTruncated
+	}
+
+	riffSize := int(binary.LittleEndian.Uint32(data[4:8]))
+	if riffSize+8 > len(data) {
+		return fmt.Errorf("webp data truncated: expected %d bytes, got %d", riffSize+8, len(data))
+	}
+
+	switch chunk := string(data[12:16]); chunk {
+	case "VP8 ", "VP8L", "VP8X":
+	default:
+		return fmt.Errorf("unexpected webp chunk %q", chunk)
+	}
+
+	chunkSize := int(binary.LittleEndian.Uint32(data[16:20]))
+	if chunkSize+20 > len(data) {
+		return fmt.Errorf("webp chunk truncated: expected %d bytes, got %d", chunkSize+20, len(data))
+	}
+	return nil
+}
+
+type Page struct {
+	Data   []byte
+	Format Format
+}
+
+func Prepare(data []byte, options Options) ([]Page, error) {
+	format, err := Sniff(data)
+	if err != nil {
+		return nil, err
+	}
+
+	page := Page{Data: data, Format: format}
+	transcode := options.Transcode && !format.ReaderSupported()
+	if !options.Optimize && !options.Crop && !options.Spreads.enabled() && !transcode {
+		return []Page{page}, nil
+	}
+
+	source, err := decode(data)
+	if errors.Is(err, ErrDecoderUnavailable) {
+		if transcode {
+			return nil, fmt.Errorf("%w: %s pages cannot be transcoded", ErrDecoderUnavailable, format)
+		}
+		return []Page{page}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cropped := source
+	if options.Crop {
+		cropped = cropMargins(source)
+	}
+
+	images := handleSpread(cropped, options)
+	if len(images) == 1 && images[0] == source && !options.Optimize && !transcode {
+		return []Page{page}, nil
+	}
+
+	pages := make([]Page, 0, len(images))
+	for _, img := range images {
+		if options.Optimize {
+			img = Optimize(img, options)
+		}
+		encoded, err := encode(img, format, options)
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, encoded)
+	}
+	return pages, nil
+}
@@ -0,0 +1,112 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/gif"
+	"image/png"
+	"testing"
+)
+
+func encodedImage(t *testing.T, format Format) []byte {
+	t.Helper()
+	source := image.NewGray(image.Rect(0, 0, 2, 2))
+
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case PNG:
+		err = png.Encode(&buf, source)
+	case GIF:
+		err = gif.Encode(&buf, source, nil)
+	default:
+		t.Fatalf("unsupported test format %s", format)
+	}
+	if err != nil {
+		t.Fatalf("unable to encode %s: %v", format, err)
+	}
+	return buf.Bytes()
+}
+
+func webPContainer(payload []byte) []byte {
+	data := []byte("RIFF\x00\x00\x00\x00WEBPVP8L\x00\x00\x00\x00")
+	data = append(data, payload...)
+	binary.LittleEndian.PutUint32(data[4:8], uint32(len(data)-8))
+	binary.LittleEndian.PutUint32(data[16:20], uint32(len(payload)))
+	return data
+}
+
+func TestValidateDetectsFormats(t *testing.T) {
+	tests := []struct {
+		data      []byte
+		format    Format
+		extension string
+	}{
+		{data: encodedImage(t, PNG), format: PNG, extension: "png"},
+		{data: encodedImage(t, GIF), format: GIF, extension: "gif"},
+		{data: webPContainer([]byte{0x2f, 0, 0, 0, 0}), format: WebP, extension: "webp"},
+	}
+
+	for _, test := range tests {
+		format, err := Validate(test.data)
+		if err != nil {
+			t.Fatalf("Validate(%s) returned %v", test.format, err)
+		}
+		if format != test.format || format.Extension() != test.extension {
+			t.Fatalf("expected %s/%s, got %s/%s", test.format, test.extension, format, format.Extension())
+		}
+	}
+}
+
+func TestValidateRejectsBadImages(t *testing.T) {
+	pngData := encodedImage(t, PNG)
+	webP := webPContainer([]byte{0x2f, 0, 0, 0, 0})
+
+	if _, err := Validate([]byte("<html>not found</html>")); !errors.Is(err, ErrUnknownFormat) {
+		t.Fatalf("expected unknown format error, got %v", err)
+	}
+	if _, err := Validate(pngData[:len(pngData)/2]); !errors.Is(err, ErrCorruptImage) {
+		t.Fatalf("expected corrupt png error, got %v", err)
+	}
+	if _, err := Validate(webP[:len(webP)-2]); !errors.Is(err, ErrCorruptImage) {
+		t.Fatalf("expected corrupt webp error, got %v", err)
+	}
+}
+
+func preparePage(t *testing.T, data []byte, options Options) Page {
+	t.Helper()
+	pages, err := Prepare(data, options)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(pages) != 1 {
+		t.Fatalf("expected a single page, got %d", len(pages))
+	}
+	return pages[0]
+}
+
+func TestPrepareTranscodesUnsupportedFormats(t *testing.T) {
+	gifData := encodedImage(t, GIF)
+
+	if page := preparePage(t, gifData, Options{}); page.Format != GIF {
+		t.Fatalf("expected gif to be kept without transcoding, got %s", page.Format)
+	}
+
+	page := preparePage(t, gifData, Options{Transcode: true})
+	if page.Format != PNG {
+		t.Fatalf("expected gif to be transcoded to png, got %s", page.Format)
+	}
+	if format, err := Sniff(page.Data); err != nil || format != PNG {
+		t.Fatalf("expected transcoded data to be png, got %s (%v)", format, err)
+	}
+
+	webP := webPContainer([]byte{0x2f, 0, 0, 0, 0})
+	if page := preparePage(t, webP, Options{Optimize: true}); page.Format != WebP {
+		t.Fatalf("expected webp to be kept when no decoder is available, got %s", page.Format)
+	}
+	if _, err := Prepare(webP, Options{Transcode: true}); !errors.Is(err, ErrDecoderUnavailable) {
+		t.Fatalf("expected webp transcoding to fail without a decoder, got %v", err)
+	}
+}
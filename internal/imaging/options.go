@@ -0,0 +1,88 @@
+package imaging
+
+import "strings"
+
+const DefaultQuality = 85
+
+type Resolution struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+type Options struct {
+	Transcode   bool                  `json:"transcode,omitempty"`
+	Optimize    bool                  `json:"optimize,omitempty"`
+	Grayscale   bool                  `json:"grayscale,omitempty"`
+	Gamma       float64               `json:"gamma,omitempty"`
+	Contrast    float64               `json:"contrast,omitempty"`
+	Width       int                   `json:"width,omitempty"`
+	Height      int                   `json:"height,omitempty"`
+	Quality     int                   `json:"quality,omitempty"`
+	Crop        bool                  `json:"crop,omitempty"`
+	Spreads     SpreadMode            `json:"spreads,omitempty"`
+	RightToLeft bool                  `json:"-"`
+	Devices     map[string]Resolution `json:"devices,omitempty"`
+}
+
+var deviceResolutions = map[string]Resolution{
+	"go6":          {Width: 1072, Height: 1448},
+	"poke5":        {Width: 1072, Height: 1448},
+	"go7":          {Width: 1264, Height: 1680},
+	"leaf3":        {Width: 1264, Height: 1680},
+	"leaf3c":       {Width: 1264, Height: 1680},
+	"page":         {Width: 1264, Height: 1680},
+	"palma":        {Width: 824, Height: 1648},
+	"tabminic":     {Width: 1404, Height: 1872},
+	"noteair2plus": {Width: 1404, Height: 1872},
+	"noteair3":     {Width: 1404, Height: 1872},
+	"noteair3c":    {Width: 1404, Height: 1872},
+	"noteair4c":    {Width: 1404, Height: 1872},
+	"go103":        {Width: 1860, Height: 2480},
+	"tabultra":     {Width: 1650, Height: 2200},
+	"tabultrac":    {Width: 1650, Height: 2200},
+	"tabultracpro": {Width: 1650, Height: 2200},
+	"tabx":         {Width: 1650, Height: 2200},
+	"notemax":      {Width: 2400, Height: 3200},
+}
+
+func (options Options) ForDevice(model string) Options {
+	if options.Optimize && options.Width == 0 && options.Height == 0 {
+		if resolution, ok := lookupResolution(options.Devices, model); ok {
+			options.Width, options.Height = resolution.Width, resolution.Height
+		}
+	}
+	options.Devices = nil
+	return options
+}
+
+func (options Options) JPEGQuality() int {
+	if options.Quality < 1 || options.Quality > 100 {
+		return DefaultQuality
+	}
+	return options.Quality
+}
+
+func lookupResolution(overrides map[string]Resolution, model string) (Resolution, bool) {
+	key := deviceKey(model)
+	if key == "" {
+		return Resolution{}, false
+	}
+	for name, resolution := range overrides {
+		if deviceKey(name) == key {
+			return resolution, true
+		}
+	}
+	resolution, ok := deviceResolutions[key]
+	return resolution, ok
+}
+
+func deviceKey(model string) string {
+	model = strings.ToLower(model)
+	model = strings.TrimPrefix(strings.TrimSpace(model), "boox")
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return -1
+	}, model)
+}
@@ -0,0 +1,113 @@
+package imaging
+
+import (
+	"image"
+	"image/draw"
+	"math"
+)
+
+func Optimize(source image.Image, options Options) image.Image {
+	var pixels []uint8
+	var stride, channels int
+	var result image.Image
+
+	bounds := source.Bounds()
+	if options.Grayscale {
+		gray := image.NewGray(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+		draw.Draw(gray, gray.Bounds(), source, bounds.Min, draw.Src)
+		pixels, stride, channels, result = gray.Pix, gray.Stride, 1, gray
+	} else {
+		rgba := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+		draw.Draw(rgba, rgba.Bounds(), source, bounds.Min, draw.Src)
+		pixels, stride, channels, result = rgba.Pix, rgba.Stride, 4, rgba
+	}
+
+	if table, ok := toneTable(options.Gamma, options.Contrast); ok {
+		for index := range pixels {
+			if channels == 4 && index%4 == 3 {
+				continue
+			}
+			pixels[index] = table[pixels[index]]
+		}
+	}
+
+	width, height := fitWithin(bounds.Dx(), bounds.Dy(), options.Width, options.Height)
+	if width == bounds.Dx() && height == bounds.Dy() {
+		return result
+	}
+
+	if channels == 1 {
+		resized := image.NewGray(image.Rect(0, 0, width, height))
+		downscale(pixels, stride, bounds.Dx(), bounds.Dy(), resized.Pix, resized.Stride, width, height, channels)
+		return resized
+	}
+	resized := image.NewNRGBA(image.Rect(0, 0, width, height))
+	downscale(pixels, stride, bounds.Dx(), bounds.Dy(), resized.Pix, resized.Stride, width, height, channels)
+	return resized
+}
+
+func toneTable(gamma, contrast float64) ([256]uint8, bool) {
+	var table [256]uint8
+	if (gamma <= 0 || gamma == 1) && contrast == 0 {
+		return table, false
+	}
+
+	for index := range table {
+		value := float64(index) / 255
+		if gamma > 0 {
+			value = math.Pow(value, 1/gamma)
+		}
+		value = (value-0.5)*(1+contrast) + 0.5
+		table[index] = uint8(math.Round(math.Max(0, math.Min(1, value)) * 255))
+	}
+	return table, true
+}
+
+func fitWithin(width, height, maxWidth, maxHeight int) (int, int) {
+	scale := 1.0
+	if maxWidth > 0 && width > maxWidth {
+		scale = float64(maxWidth) / float64(width)
+	}
+	if maxHeight > 0 && height > maxHeight {
+		scale = math.Min(scale, float64(maxHeight)/float64(height))
+	}
+	if scale >= 1 {
+		return width, height
+	}
+	return max(1, int(math.Round(float64(width)*scale))), max(1, int(math.Round(float64(height)*scale)))
+}
+
+func downscale(src []uint8, srcStride, srcWidth, srcHeight int, dst []uint8, dstStride, dstWidth, dstHeight, channels int) {
+	xScale := float64(srcWidth) / float64(dstWidth)
+	yScale := float64(srcHeight) / float64(dstHeight)
+	sums := make([]float64, channels)
+
+	for y := 0; y < dstHeight; y++ {
+		top, bottom := float64(y)*yScale, float64(y+1)*yScale
+		for x := 0; x < dstWidth; x++ {
+			left, right := float64(x)*xScale, float64(x+1)*xScale
+			for channel := range sums {
+				sums[channel] = 0
+			}
+			area := 0.0
+
+			for sy := int(top); sy < srcHeight && float64(sy) < bottom; sy++ {
+				yWeight := math.Min(bottom, float64(sy+1)) - math.Max(top, float64(sy))
+				row := sy * srcStride
+				for sx := int(left); sx < srcWidth && float64(sx) < right; sx++ {
+					weight := yWeight * (math.Min(right, float64(sx+1)) - math.Max(left, float64(sx)))
+					offset := row + sx*channels
+					for channel := range sums {
+						sums[channel] += float64(src[offset+channel]) * weight
+					}
+					area += weight
+				}
+			}
+
+			offset := y*dstStride + x*channels
+			for channel, sum := range sums {
+				dst[offset+channel] = uint8(math.Round(sum / area))
+			}
+		}
+	}
+}
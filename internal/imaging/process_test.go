@@ -0,0 +1,101 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func TestPrepareOptimizesPagesForEInk(t *testing.T) {
+	source := image.NewNRGBA(image.Rect(0, 0, 400, 600))
+	for y := 0; y < 600; y++ {
+		for x := 0; x < 400; x++ {
+			source.Set(x, y, color.NRGBA{R: uint8(x), G: 40, B: uint8(y), A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, source); err != nil {
+		t.Fatalf("unable to encode source: %v", err)
+	}
+
+	page := preparePage(t, buf.Bytes(), Options{Optimize: true, Grayscale: true, Gamma: 1.2, Contrast: 0.1, Width: 200, Height: 200, Quality: 70})
+	if page.Format != JPEG {
+		t.Fatalf("expected optimised page to be jpeg, got %s", page.Format)
+	}
+
+	decoded, err := jpeg.Decode(bytes.NewReader(page.Data))
+	if err != nil {
+		t.Fatalf("unable to decode optimised page: %v", err)
+	}
+	if _, ok := decoded.(*image.Gray); !ok {
+		t.Fatalf("expected grayscale jpeg, got %T", decoded)
+	}
+	if size := decoded.Bounds().Size(); size.X != 133 || size.Y != 200 {
+		t.Fatalf("expected page to fit 200x200 keeping aspect ratio, got %v", size)
+	}
+}
+
+func TestOptimizeNeverUpscales(t *testing.T) {
+	source := image.NewGray(image.Rect(0, 0, 10, 20))
+	result := Optimize(source, Options{Width: 100, Height: 200})
+	if size := result.Bounds().Size(); size.X != 10 || size.Y != 20 {
+		t.Fatalf("expected original size, got %v", size)
+	}
+}
+
+func TestDownscaleAveragesPixels(t *testing.T) {
+	source := image.NewGray(image.Rect(0, 0, 2, 2))
+	source.Pix = []uint8{0, 255, 255, 0}
+
+	result := Optimize(source, Options{Grayscale: true, Width: 1, Height: 1}).(*image.Gray)
+	if result.Pix[0] != 128 {
+		t.Fatalf("expected averaged pixel 128, got %d", result.Pix[0])
+	}
+}
+
+func TestToneTable(t *testing.T) {
+	if _, ok := toneTable(1, 0); ok {
+		t.Fatalf("expected identity settings to skip the tone table")
+	}
+
+	table, ok := toneTable(1, 0.5)
+	if !ok {
+		t.Fatalf("expected contrast to build a tone table")
+	}
+	if table[0] != 0 || table[255] != 255 || table[64] >= 64 || table[192] <= 192 {
+		t.Fatalf("expected contrast to push values away from mid-grey: %d %d", table[64], table[192])
+	}
+
+	table, _ = toneTable(2, 0)
+	if table[64] <= 64 {
+		t.Fatalf("expected gamma above 1 to lighten shadows, got %d", table[64])
+	}
+}
+
+func TestForDeviceResolvesResolution(t *testing.T) {
+	if options := (Options{}).ForDevice("NoteAir3C"); options.Width != 0 {
+		t.Fatalf("expected resolution to be left unset when optimisation is off")
+	}
+
+	options := Options{Optimize: true}.ForDevice("BOOX Note Air3 C")
+	if options.Width != 1404 || options.Height != 1872 {
+		t.Fatalf("expected built-in Note Air 3 C resolution, got %dx%d", options.Width, options.Height)
+	}
+
+	custom := Options{Optimize: true, Devices: map[string]Resolution{"Note Air3 C": {Width: 1000, Height: 1400}}}.ForDevice("NoteAir3C")
+	if custom.Width != 1000 || custom.Height != 1400 || custom.Devices != nil {
+		t.Fatalf("expected configured override, got %+v", custom)
+	}
+
+	explicit := Options{Optimize: true, Width: 800}.ForDevice("NoteAir3C")
+	if explicit.Width != 800 || explicit.Height != 0 {
+		t.Fatalf("expected explicit size to win, got %dx%d", explicit.Width, explicit.Height)
+	}
+
+	if unknown := (Options{Optimize: true}).ForDevice("Mystery"); unknown.Width != 0 || unknown.Height != 0 {
+		t.Fatalf("expected unknown device to leave size unset, got %+v", unknown)
+	}
+}
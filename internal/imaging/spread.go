@@ -0,0 +1,114 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"strings"
+)
+
+type SpreadMode string
+
+const (
+	SpreadsOff    SpreadMode = "off"
+	SpreadsSplit  SpreadMode = "split"
+	SpreadsRotate SpreadMode = "rotate"
+)
+
+func ParseSpreadMode(value string) (SpreadMode, error) {
+	switch mode := SpreadMode(strings.ToLower(strings.TrimSpace(value))); mode {
+	case "", SpreadsOff:
+		return SpreadsOff, nil
+	case SpreadsSplit, SpreadsRotate:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown spread mode %q (expected off, split or rotate)", value)
+	}
+}
+
+func (mode SpreadMode) Next() SpreadMode {
+	switch mode {
+	case SpreadsSplit:
+		return SpreadsRotate
+	case SpreadsRotate:
+		return SpreadsOff
+	default:
+		return SpreadsSplit
+	}
+}
+
+func (mode SpreadMode) enabled() bool {
+	return mode == SpreadsSplit || mode == SpreadsRotate
+}
+
+func isSpread(bounds image.Rectangle) bool {
+	return bounds.Dx() > bounds.Dy()
+}
+
+func handleSpread(source image.Image, options Options) []image.Image {
+	bounds := source.Bounds()
+	if !options.Spreads.enabled() || !isSpread(bounds) {
+		return []image.Image{source}
+	}
+
+	if options.Spreads == SpreadsRotate {
+		return []image.Image{rotate(source, !options.RightToLeft)}
+	}
+
+	middle := bounds.Min.X + bounds.Dx()/2
+	left := subImage(source, image.Rect(bounds.Min.X, bounds.Min.Y, middle, bounds.Max.Y))
+	right := subImage(source, image.Rect(middle, bounds.Min.Y, bounds.Max.X, bounds.Max.Y))
+	if options.RightToLeft {
+		return []image.Image{right, left}
+	}
+	return []image.Image{left, right}
+}
+
+func subImage(source image.Image, rect image.Rectangle) image.Image {
+	if sub, ok := source.(interface {
+		SubImage(image.Rectangle) image.Image
+	}); ok {
+		return sub.SubImage(rect)
+	}
+
+	copied := image.NewNRGBA(image.Rect(0, 0, rect.Dx(), rect.Dy()))
+	draw.Draw(copied, copied.Bounds(), source, rect.Min, draw.Src)
+	return copied
+}
+
+func rotate(source image.Image, clockwise bool) image.Image {
+	bounds := source.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	var pixels []uint8
+	var stride, channels int
+	if gray, ok := source.(*image.Gray); ok {
+		pixels, stride, channels = gray.Pix[gray.PixOffset(bounds.Min.X, bounds.Min.Y):], gray.Stride, 1
+	} else {
+		rgba := image.NewNRGBA(image.Rect(0, 0, width, height))
+		draw.Draw(rgba, rgba.Bounds(), source, bounds.Min, draw.Src)
+		pixels, stride, channels = rgba.Pix, rgba.Stride, 4
+	}
+
+	var result image.Image
+	var dst []uint8
+	var dstStride int
+	if channels == 1 {
+		rotated := image.NewGray(image.Rect(0, 0, height, width))
+		result, dst, dstStride = rotated, rotated.Pix, rotated.Stride
+	} else {
+		rotated := image.NewNRGBA(image.Rect(0, 0, height, width))
+		result, dst, dstStride = rotated, rotated.Pix, rotated.Stride
+	}
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			dx, dy := y, width-1-x
+			if clockwise {
+				dx, dy = height-1-y, x
+			}
+			copy(dst[dy*dstStride+dx*channels:dy*dstStride+dx*channels+channels], pixels[y*stride+x*channels:y*stride+x*channels+channels])
+		}
+	}
+	return result
+}
@@ -0,0 +1,105 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"testing"
+)
+
+func spreadPage(t *testing.T) []byte {
+	t.Helper()
+	spread := image.NewGray(image.Rect(0, 0, 4, 2))
+	for y := 0; y < 2; y++ {
+		for x := 2; x < 4; x++ {
+			spread.Pix[y*spread.Stride+x] = 255
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, spread); err != nil {
+		t.Fatalf("unable to encode spread: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func firstPixel(t *testing.T, page Page) (image.Point, uint8) {
+	t.Helper()
+	decoded, err := png.Decode(bytes.NewReader(page.Data))
+	if err != nil {
+		t.Fatalf("unable to decode page: %v", err)
+	}
+	gray, ok := decoded.(*image.Gray)
+	if !ok {
+		t.Fatalf("expected grayscale page, got %T", decoded)
+	}
+	return gray.Bounds().Size(), gray.Pix[0]
+}
+
+func TestPrepareSplitsSpreadsInReadingOrder(t *testing.T) {
+	tests := []struct {
+		rightToLeft bool
+		first       uint8
+	}{
+		{rightToLeft: true, first: 255},
+		{rightToLeft: false, first: 0},
+	}
+
+	for _, test := range tests {
+		pages, err := Prepare(spreadPage(t), Options{Spreads: SpreadsSplit, RightToLeft: test.rightToLeft})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(pages) != 2 {
+			t.Fatalf("expected spread to become two pages, got %d", len(pages))
+		}
+		size, pixel := firstPixel(t, pages[0])
+		if size.X != 2 || size.Y != 2 {
+			t.Fatalf("expected 2x2 half page, got %v", size)
+		}
+		if pixel != test.first {
+			t.Fatalf("rightToLeft=%v: expected first page to start with %d, got %d", test.rightToLeft, test.first, pixel)
+		}
+	}
+}
+
+func TestPrepareRotatesSpreadsTowardsFirstPage(t *testing.T) {
+	tests := []struct {
+		rightToLeft bool
+		first       uint8
+	}{
+		{rightToLeft: true, first: 255},
+		{rightToLeft: false, first: 0},
+	}
+
+	for _, test := range tests {
+		page := preparePage(t, spreadPage(t), Options{Spreads: SpreadsRotate, RightToLeft: test.rightToLeft})
+		size, pixel := firstPixel(t, page)
+		if size.X != 2 || size.Y != 4 {
+			t.Fatalf("expected rotated 2x4 page, got %v", size)
+		}
+		if pixel != test.first {
+			t.Fatalf("rightToLeft=%v: expected first half of the spread on top, got pixel %d", test.rightToLeft, pixel)
+		}
+	}
+}
+
+func TestPrepareKeepsPortraitPagesUntouched(t *testing.T) {
+	portrait := encodedImage(t, PNG)
+	page := preparePage(t, portrait, Options{Spreads: SpreadsSplit})
+	if !bytes.Equal(page.Data, portrait) {
+		t.Fatalf("expected portrait page to be passed through unchanged")
+	}
+}
+
+func TestParseSpreadMode(t *testing.T) {
+	for value, want := range map[string]SpreadMode{"": SpreadsOff, "Split": SpreadsSplit, "rotate": SpreadsRotate} {
+		mode, err := ParseSpreadMode(value)
+		if err != nil || mode != want {
+			t.Fatalf("ParseSpreadMode(%q) = %s, %v", value, mode, err)
+		}
+	}
+	if _, err := ParseSpreadMode("flip"); err == nil {
+		t.Fatalf("expected error for unknown spread mode")
+	}
+}
@@ -0,0 +1,81 @@
+package manga
+
+import (
+	"fmt"
+	"strings"
+)
+
+type DedupeMode string
+
+const (
+	DedupeAll  DedupeMode = "all"
+	DedupeBest DedupeMode = "best"
+)
+
+var DedupeModes = []DedupeMode{DedupeAll, DedupeBest}
+
+func ParseDedupeMode(value string) (DedupeMode, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return DedupeAll, nil
+	}
+	for _, mode := range DedupeModes {
+		if string(mode) == value {
+			return mode, nil
+		}
+	}
+	return "", fmt.Errorf("unknown dedupe mode %q (expected all or best)", value)
+}
+
+func (mode DedupeMode) Next() DedupeMode {
+	for index, candidate := range DedupeModes {
+		if candidate == mode {
+			return DedupeModes[(index+1)%len(DedupeModes)]
+		}
+	}
+	return DedupeBest
+}
+
+func DedupeChapters(chapters []Chapter, mode DedupeMode, groups []string) []Chapter {
+	if mode != DedupeBest {
+		return chapters
+	}
+
+	best := map[string]int{}
+	for index, chapter := range chapters {
+		if chapter.Number == "" {
+			continue
+		}
+		current, ok := best[chapter.Number]
+		if !ok || betterUpload(chapter, chapters[current], groups) {
+			best[chapter.Number] = index
+		}
+	}
+
+	deduped := make([]Chapter, 0, len(best))
+	for index, chapter := range chapters {
+		if chapter.Number == "" || best[chapter.Number] == index {
+			deduped = append(deduped, chapter)
+		}
+	}
+	return deduped
+}
+
+func betterUpload(candidate, current Chapter, groups []string) bool {
+	if candidateRank, currentRank := groupRank(candidate.Group, groups), groupRank(current.Group, groups); candidateRank != currentRank {
+		return candidateRank < currentRank
+	}
+	if !candidate.PublishedAt.Equal(current.PublishedAt) {
+		return candidate.PublishedAt.After(current.PublishedAt)
+	}
+	return candidate.Pages > current.Pages
+}
+
+func groupRank(group string, groups []string) int {
+	for index, preferred := range groups {
+		if group != "" && strings.EqualFold(strings.TrimSpace(preferred), group) {
+			return index
+		}
+	}
+	return len(groups)
+}
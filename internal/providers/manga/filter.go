@@ -0,0 +1,123 @@
+package manga
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	ContentRatings        = []string{"safe", "suggestive", "erotica", "pornographic"}
+	DefaultContentRatings = []string{"safe", "suggestive", "erotica"}
+	Statuses              = []string{"ongoing", "completed", "hiatus", "cancelled"}
+	Demographics          = []string{"shounen", "shoujo", "seinen", "josei", "none"}
+)
+
+type SearchOrder string
+
+const (
+	OrderRelevance SearchOrder = "relevance"
+	OrderLatest    SearchOrder = "latest"
+	OrderPopular   SearchOrder = "popular"
+	OrderRating    SearchOrder = "rating"
+	OrderNewest    SearchOrder = "newest"
+	OrderTitle     SearchOrder = "title"
+	OrderYear      SearchOrder = "year"
+)
+
+var SearchOrders = []SearchOrder{OrderRelevance, OrderLatest, OrderPopular, OrderRating, OrderNewest, OrderTitle, OrderYear}
+
+func ParseSearchOrder(value string) (SearchOrder, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return OrderRelevance, nil
+	}
+	for _, order := range SearchOrders {
+		if string(order) == value {
+			return order, nil
+		}
+	}
+	return "", fmt.Errorf("unknown sort order %q (expected relevance, latest, popular, rating, newest, title or year)", value)
+}
+
+func (order SearchOrder) Next() SearchOrder {
+	for index, candidate := range SearchOrders {
+		if candidate == order {
+			return SearchOrders[(index+1)%len(SearchOrders)]
+		}
+	}
+	return OrderRelevance
+}
+
+type SearchFilter struct {
+	ContentRatings    []string    `json:"content_ratings,omitempty"`
+	Statuses          []string    `json:"statuses,omitempty"`
+	Demographics      []string    `json:"demographics,omitempty"`
+	OriginalLanguages []string    `json:"original_languages,omitempty"`
+	IncludedTags      []string    `json:"included_tags,omitempty"`
+	ExcludedTags      []string    `json:"excluded_tags,omitempty"`
+	Year              int         `json:"year,omitempty"`
+	Order             SearchOrder `json:"order,omitempty"`
+}
+
+func (filter SearchFilter) Validate() error {
+	checks := []struct {
+		name    string
+		values  []string
+		allowed []string
+	}{
+		{name: "content rating", values: filter.ContentRatings, allowed: ContentRatings},
+		{name: "status", values: filter.Statuses, allowed: Statuses},
+		{name: "demographic", values: filter.Demographics, allowed: Demographics},
+	}
+	for _, check := range checks {
+		for _, value := range check.values {
+			if !contains(check.allowed, value) {
+				return fmt.Errorf("unknown %s %q (expected %s)", check.name, value, strings.Join(check.allowed, ", "))
+			}
+		}
+	}
+	if filter.Year < 0 {
+		return fmt.Errorf("year must be positive")
+	}
+	if _, err := ParseSearchOrder(string(filter.Order)); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (filter SearchFilter) Active() bool {
+	return len(filter.Statuses) > 0 || len(filter.Demographics) > 0 || len(filter.OriginalLanguages) > 0 ||
+		len(filter.IncludedTags) > 0 || len(filter.ExcludedTags) > 0 || filter.Year > 0
+}
+
+func ParseList(value string) []string {
+	values := []string{}
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.ToLower(strings.TrimSpace(part)); part != "" && !contains(values, part) {
+			values = append(values, part)
+		}
+	}
+	return values
+}
+
+func ParseYear(value string) (int, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+	year, err := strconv.Atoi(value)
+	if err != nil || year < 0 {
+		return 0, fmt.Errorf("year must be a number")
+	}
+	return year, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package manga
+
+import "strings"
+
+const DefaultLanguage = "en"
+
+type Language struct {
+	Code string
+	Name string
+}
+
+var Languages = []Language{
+	{Code: "en", Name: "English"},
+	{Code: "es", Name: "Spanish"},
+	{Code: "es-la", Name: "Spanish (Latin America)"},
+	{Code: "pt-br", Name: "Portuguese (Brazil)"},
+	{Code: "pt", Name: "Portuguese"},
+	{Code: "fr", Name: "French"},
+	{Code: "de", Name: "German"},
+	{Code: "it", Name: "Italian"},
+	{Code: "ru", Name: "Russian"},
+	{Code: "uk", Name: "Ukrainian"},
+	{Code: "pl", Name: "Polish"},
+	{Code: "tr", Name: "Turkish"},
+	{Code: "ar", Name: "Arabic"},
+	{Code: "id", Name: "Indonesian"},
+	{Code: "vi", Name: "Vietnamese"},
+	{Code: "th", Name: "Thai"},
+	{Code: "zh", Name: "Chinese (Simplified)"},
+	{Code: "zh-hk", Name: "Chinese (Traditional)"},
+	{Code: "ko", Name: "Korean"},
+	{Code: "ja", Name: "Japanese"},
+}
+
+func LanguageName(code string) string {
+	for _, language := range Languages {
+		if strings.EqualFold(language.Code, code) {
+			return language.Name
+		}
+	}
+	return code
+}
+
+func NormalizeLanguages(codes []string) []string {
+	normalized := []string{}
+	seen := map[string]bool{}
+	for _, code := range codes {
+		code = strings.ToLower(strings.TrimSpace(code))
+		if code == "" || seen[code] {
+			continue
+		}
+		seen[code] = true
+		normalized = append(normalized, code)
+	}
+	if len(normalized) == 0 {
+		return []string{DefaultLanguage}
+	}
+	return normalized
+}
@@ -1,6 +1,7 @@
 package mangadex
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -11,20 +12,50 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/ssh-vom/boox-serve/internal/imaging"
 	"github.com/ssh-vom/boox-serve/internal/providers/manga"
 )
 
 const (
 	baseURL           = "https://api.mangadex.org"
 	coverBaseURL      = "https://uploads.mangadex.org"
+	reportEndpoint    = "https://api.mangadex.network/report"
 	mangaDexUserAgent = "boox-serve/0.1"
+	pageWorkers       = 4
+	pageAttempts      = 3
+	nodeAttempts      = 3
+	reportTimeout     = 5 * time.Second
+	reportQueueSize   = 64
+	chapterIDBatch    = 100
+	searchPageSize    = 20
+	searchWindow      = 10000
 )
 
+var searchOrders = map[manga.SearchOrder][2]string{
+	manga.OrderRelevance: {"relevance", "desc"},
+	manga.OrderLatest:    {"latestUploadedChapter", "desc"},
+	manga.OrderPopular:   {"followedCount", "desc"},
+	manga.OrderRating:    {"rating", "desc"},
+	manga.OrderNewest:    {"createdAt", "desc"},
+	manga.OrderTitle:     {"title", "asc"},
+	manga.OrderYear:      {"year", "desc"},
+}
+
 type Provider struct {
-	httpClient *http.Client
-	apiKey     string
+	httpClient     *http.Client
+	apiKey         string
+	reportURL      string
+	languages      []string
+	contentRatings []string
+
+	tagsMu sync.Mutex
+	tags   map[string]string
+
+	reportOnce sync.Once
+	reports    chan deliveryReport
 }
 
 func New(httpClient *http.Client, apiKey string) *Provider {
@@ -32,43 +63,72 @@ func New(httpClient *http.Client, apiKey string) *Provider {
 		httpClient = &http.Client{Timeout: 30 * time.Second}
 	}
 
-	return &Provider{httpClient: httpClient, apiKey: strings.TrimSpace(apiKey)}
+	return &Provider{httpClient: httpClient, apiKey: strings.TrimSpace(apiKey), reportURL: reportEndpoint, languages: manga.NormalizeLanguages(nil), contentRatings: manga.DefaultContentRatings, reports: make(chan deliveryReport, reportQueueSize)}
+}
+
+func (provider *Provider) WithLanguages(languages []string) *Provider {
+	provider.languages = manga.NormalizeLanguages(languages)
+	return provider
 }
 
-func (provider *Provider) Search(ctx context.Context, query string) ([]manga.SearchResult, error) {
+func (provider *Provider) Search(ctx context.Context, query string, filter manga.SearchFilter, offset int) (manga.SearchPage, error) {
+	page := manga.SearchPage{Next: offset}
+	if err := filter.Validate(); err != nil {
+		return page, err
+	}
+	if offset < 0 {
+		return page, fmt.Errorf("search offset must not be negative")
+	}
+	limit := min(searchPageSize, searchWindow-offset)
+	if limit <= 0 {
+		page.Total = offset
+		return page, nil
+	}
+
 	searchURL, err := url.Parse(baseURL + "/manga")
 	if err != nil {
-		return nil, fmt.Errorf("error parsing search URL: %w", err)
+		return page, fmt.Errorf("error parsing search URL: %w", err)
 	}
 
 	q := searchURL.Query()
-	q.Set("title", query)
-	q.Set("limit", "20")
+	if query = strings.TrimSpace(query); query != "" {
+		q.Set("title", query)
+	}
+	q.Set("limit", strconv.Itoa(limit))
+	q.Set("offset", strconv.Itoa(offset))
 	q.Add("includes[]", "cover_art")
+	if err := provider.addFilter(ctx, q, query, filter); err != nil {
+		return page, err
+	}
 	searchURL.RawQuery = q.Encode()
 
 	request, err := http.NewRequestWithContext(ctx, http.MethodGet, searchURL.String(), nil)
 	if err != nil {
-		return nil, fmt.Errorf("error building search request: %w", err)
+		return page, fmt.Errorf("error building search request: %w", err)
 	}
 	provider.addHeaders(request)
 
 	response, err := provider.httpClient.Do(request)
 	if err != nil {
-		return nil, fmt.Errorf("error making search request: %w", err)
+		return page, fmt.Errorf("error making search request: %w", err)
 	}
 	defer response.Body.Close()
 
 	if response.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("search request failed: %s", response.Status)
+		return page, fmt.Errorf("search request failed: %s", response.Status)
 	}
 
 	var result mangaSearchResponse
 	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("error parsing search response: %w", err)
+		return page, fmt.Errorf("error parsing search response: %w", err)
 	}
 
-	results := make([]manga.SearchResult, 0, len(result.Data))
+	page.Results = make([]manga.SearchResult, 0, len(result.Data))
+	page.Next = offset + len(result.Data)
+	page.Total = min(result.Total, searchWindow)
+	if len(result.Data) == 0 {
+		page.Total = page.Next
+	}
 	for _, entry := range result.Data {
 		title := pickTitle(entry.Attributes.Title)
 		if title == "" {
@@ -77,22 +137,240 @@ func (provider *Provider) Search(ctx context.Context, query string) ([]manga.Sea
 
 		coverFileName := pickCoverFileName(entry.Relationships)
 		coverURL := buildCoverURL(entry.ID, coverFileName)
-		results = append(results, manga.SearchResult{ID: entry.ID, Title: title, CoverURL: coverURL})
+		page.Results = append(page.Results, manga.SearchResult{ID: entry.ID, Title: title, CoverURL: coverURL})
+	}
+
+	return page, nil
+}
+
+func (provider *Provider) WithContentRatings(ratings []string) *Provider {
+	provider.contentRatings = manga.DefaultContentRatings
+	if len(ratings) > 0 {
+		provider.contentRatings = ratings
+	}
+	return provider
+}
+
+func (provider *Provider) contentRatingQuery() string {
+	values := url.Values{}
+	for _, rating := range provider.contentRatings {
+		values.Add("contentRating[]", rating)
+	}
+	return values.Encode()
+}
+
+func (provider *Provider) addFilter(ctx context.Context, q url.Values, query string, filter manga.SearchFilter) error {
+	ratings := filter.ContentRatings
+	if len(ratings) == 0 {
+		ratings = provider.contentRatings
+	}
+	for _, rating := range ratings {
+		q.Add("contentRating[]", rating)
+	}
+	for _, status := range filter.Statuses {
+		q.Add("status[]", status)
+	}
+	for _, demographic := range filter.Demographics {
+		q.Add("publicationDemographic[]", demographic)
+	}
+	for _, language := range filter.OriginalLanguages {
+		q.Add("originalLanguage[]", language)
+	}
+	if filter.Year > 0 {
+		q.Set("year", strconv.Itoa(filter.Year))
+	}
+
+	for param, names := range map[string][]string{"includedTags[]": filter.IncludedTags, "excludedTags[]": filter.ExcludedTags} {
+		for _, name := range names {
+			id, err := provider.tagID(ctx, name)
+			if err != nil {
+				return err
+			}
+			q.Add(param, id)
+		}
+	}
+
+	order, err := manga.ParseSearchOrder(string(filter.Order))
+	if err != nil {
+		return err
+	}
+	if order == manga.OrderRelevance && query == "" {
+		return nil
+	}
+	field := searchOrders[order]
+	q.Set("order["+field[0]+"]", field[1])
+	return nil
+}
+
+func (provider *Provider) tagID(ctx context.Context, name string) (string, error) {
+	provider.tagsMu.Lock()
+	defer provider.tagsMu.Unlock()
+
+	if provider.tags == nil {
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/manga/tag", nil)
+		if err != nil {
+			return "", fmt.Errorf("error building tag request: %w", err)
+		}
+		provider.addHeaders(request)
+
+		response, err := provider.httpClient.Do(request)
+		if err != nil {
+			return "", fmt.Errorf("error fetching tags: %w", err)
+		}
+		defer response.Body.Close()
+		if response.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("tag request failed: %s", response.Status)
+		}
+
+		var result tagResponse
+		if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+			return "", fmt.Errorf("error parsing tag response: %w", err)
+		}
+		tags := map[string]string{}
+		for _, tag := range result.Data {
+			if tagName := pickTitle(tag.Attributes.Name); tagName != "" {
+				tags[strings.ToLower(tagName)] = tag.ID
+			}
+		}
+		provider.tags = tags
 	}
 
-	return results, nil
+	id, ok := provider.tags[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return "", fmt.Errorf("unknown tag %q", name)
+	}
+	return id, nil
+}
+
+func (provider *Provider) FetchSeries(ctx context.Context, mangaID string) (manga.Series, error) {
+	result, err := provider.fetchManga(ctx, mangaID)
+	if err != nil {
+		return manga.Series{}, err
+	}
+	return seriesFromResponse(result), nil
+}
+
+func (provider *Provider) FetchDetails(ctx context.Context, mangaID string) (manga.SeriesDetails, error) {
+	result, err := provider.fetchManga(ctx, mangaID)
+	if err != nil {
+		return manga.SeriesDetails{}, err
+	}
+
+	series := seriesFromResponse(result)
+	attributes := result.Data.Attributes
+	return manga.SeriesDetails{
+		Series:      series,
+		AltTitles:   altTitles(series.Title, attributes.AltTitles),
+		LastVolume:  attributes.LastVolume,
+		LastChapter: attributes.LastChapter,
+		Links:       seriesLinks(result.Data.ID, attributes.Links),
+	}, nil
+}
+
+func (provider *Provider) fetchManga(ctx context.Context, mangaID string) (mangaResponse, error) {
+	endpoint := fmt.Sprintf("%s/manga/%s?includes[]=author&includes[]=artist&includes[]=cover_art", baseURL, url.PathEscape(mangaID))
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return mangaResponse{}, fmt.Errorf("error building series request: %w", err)
+	}
+	provider.addHeaders(request)
+
+	response, err := provider.httpClient.Do(request)
+	if err != nil {
+		return mangaResponse{}, fmt.Errorf("error fetching series: %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return mangaResponse{}, fmt.Errorf("series request failed: %s", response.Status)
+	}
+
+	var result mangaResponse
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		return mangaResponse{}, fmt.Errorf("error parsing series response: %w", err)
+	}
+	return result, nil
+}
+
+func seriesFromResponse(result mangaResponse) manga.Series {
+	attributes := result.Data.Attributes
+	series := manga.Series{
+		ID:            result.Data.ID,
+		Title:         pickTitle(attributes.Title),
+		Description:   strings.TrimSpace(pickTitle(attributes.Description)),
+		Language:      attributes.OriginalLanguage,
+		Status:        attributes.Status,
+		ContentRating: attributes.ContentRating,
+		Year:          attributes.Year,
+		CoverURL:      buildCoverURL(result.Data.ID, pickCoverFileName(result.Data.Relationships)),
+	}
+
+	for _, relation := range result.Data.Relationships {
+		name := strings.TrimSpace(relation.Attributes.Name)
+		if name == "" {
+			continue
+		}
+		switch relation.Type {
+		case "author":
+			series.Authors = append(series.Authors, name)
+		case "artist":
+			series.Artists = append(series.Artists, name)
+		}
+	}
+
+	for _, tag := range attributes.Tags {
+		name := pickTitle(tag.Attributes.Name)
+		if name == "" {
+			continue
+		}
+		if tag.Attributes.Group == "genre" {
+			series.Genres = append(series.Genres, name)
+		} else {
+			series.Tags = append(series.Tags, name)
+		}
+	}
+
+	return series
 }
 
 func (provider *Provider) FetchChapters(ctx context.Context, mangaID string) ([]manga.Chapter, error) {
+	languageFilter := ""
+	for _, language := range provider.languages {
+		languageFilter += "&translatedLanguage[]=" + url.QueryEscape(language)
+	}
+
+	allChapters, err := provider.fetchChapterList(ctx, func(limit, offset int) string {
+		return fmt.Sprintf("%s/chapter?limit=%d&offset=%d&manga=%s&%s&includeFutureUpdates=1&includes[]=scanlation_group&includes[]=user&order[volume]=asc&order[chapter]=asc%s", baseURL, limit, offset, mangaID, provider.contentRatingQuery(), languageFilter)
+	})
+	if err != nil {
+		return nil, err
+	}
+	allChapters = preferLanguages(allChapters, provider.languages)
+
+	fallback, err := provider.fetchFallbackChapters(ctx, mangaID, allChapters)
+	if err != nil && ctx.Err() != nil {
+		return nil, fmt.Errorf("error fetching chapters: %w", ctx.Err())
+	}
+	allChapters = append(allChapters, fallback...)
+
+	sort.Slice(allChapters, func(i, j int) bool {
+		if allChapters[i].NumericChapter == allChapters[j].NumericChapter {
+			return allChapters[i].Volume < allChapters[j].Volume
+		}
+		return allChapters[i].NumericChapter < allChapters[j].NumericChapter
+	})
+
+	return allChapters, nil
+}
+
+func (provider *Provider) fetchChapterList(ctx context.Context, endpoint func(limit, offset int) string) ([]manga.Chapter, error) {
 	var allChapters []manga.Chapter
 	seen := make(map[string]bool)
 	limit := 100
 	offset := 0
 
 	for {
-		endpoint := fmt.Sprintf("%s/chapter?limit=%d&offset=%d&manga=%s&contentRating[]=safe&contentRating[]=suggestive&contentRating[]=erotica&includeFutureUpdates=1&order[volume]=asc&order[chapter]=asc&translatedLanguage[]=en", baseURL, limit, offset, mangaID)
-
-		request, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint(limit, offset), nil)
 		if err != nil {
 			return nil, fmt.Errorf("error building image request: %w", err)
 		}
@@ -125,6 +403,11 @@ func (provider *Provider) FetchChapters(ctx context.Context, mangaID string) ([]
 				Number:         chapterData.Attributes.Chapter,
 				Title:          chapterData.Attributes.Title,
 				Volume:         chapterData.Attributes.Volume,
+				Language:       chapterData.Attributes.TranslatedLanguage,
+				Group:          relationshipNames(chapterData.Relationships, "scanlation_group"),
+				Uploader:       relationshipNames(chapterData.Relationships, "user"),
+				PublishedAt:    chapterData.Attributes.PublishAt,
+				Pages:          chapterData.Attributes.Pages,
 				NumericChapter: chapterNumber,
 			}
 			allChapters = append(allChapters, chapter)
@@ -136,23 +419,75 @@ func (provider *Provider) FetchChapters(ctx context.Context, mangaID string) ([]
 		offset += limit
 	}
 
-	sort.Slice(allChapters, func(i, j int) bool {
-		if allChapters[i].NumericChapter == allChapters[j].NumericChapter {
-			return allChapters[i].Volume < allChapters[j].Volume
+	return allChapters, nil
+}
+
+func (provider *Provider) fetchFallbackChapters(ctx context.Context, mangaID string, chapters []manga.Chapter) ([]manga.Chapter, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/manga/%s/aggregate", baseURL, mangaID), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error building aggregate request: %w", err)
+	}
+	provider.addHeaders(request)
+
+	response, err := provider.httpClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching chapter aggregate: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("chapter aggregate failed: %s", response.Status)
+	}
+
+	var aggregate aggregateResponse
+	if err := json.NewDecoder(response.Body).Decode(&aggregate); err != nil {
+		return nil, fmt.Errorf("error parsing chapter aggregate: %w", err)
+	}
+
+	covered := map[string]bool{}
+	for _, chapter := range chapters {
+		covered[chapterKey(chapter)] = true
+	}
+
+	missing := []string{}
+	for _, volume := range decodeObject[aggregateVolume](aggregate.Volumes) {
+		for number, chapter := range decodeObject[aggregateChapter](volume.Chapters) {
+			if covered[number] {
+				continue
+			}
+			if chapter.ID != "" {
+				missing = append(missing, chapter.ID)
+			}
+			missing = append(missing, chapter.Others...)
 		}
-		return allChapters[i].NumericChapter < allChapters[j].NumericChapter
-	})
+	}
+	sort.Strings(missing)
+
+	fallback := []manga.Chapter{}
+	for start := 0; start < len(missing); start += chapterIDBatch {
+		batch := missing[start:min(start+chapterIDBatch, len(missing))]
+		ids := ""
+		for _, id := range batch {
+			ids += "&ids[]=" + url.QueryEscape(id)
+		}
+		found, err := provider.fetchChapterList(ctx, func(limit, offset int) string {
+			return fmt.Sprintf("%s/chapter?limit=%d&offset=%d&%s&includeFutureUpdates=1&includes[]=scanlation_group&includes[]=user%s", baseURL, limit, offset, provider.contentRatingQuery(), ids)
+		})
+		if err != nil {
+			return nil, err
+		}
+		fallback = append(fallback, found...)
+	}
 
-	return allChapters, nil
+	return preferLanguages(fallback, fallbackLanguages(fallback)), nil
 }
 
-func (provider *Provider) DownloadChapterImages(ctx context.Context, chapter manga.Chapter) ([][]byte, error) {
+func (provider *Provider) DownloadChapterImages(ctx context.Context, chapter manga.Chapter, progress manga.PageProgress) ([][]byte, error) {
 	chapterDetails, err := provider.fetchChapterDetails(ctx, chapter.ID)
 	if err != nil {
 		return nil, err
 	}
 
-	return provider.downloadChapterImages(ctx, chapterDetails)
+	return provider.downloadChapterImages(ctx, chapter.ID, chapterDetails, progress)
 }
 
 func (provider *Provider) FetchCover(ctx context.Context, coverURL string) ([]byte, error) {
@@ -197,9 +532,36 @@ type mangaRelationship struct {
 	Type       string `json:"type"`
 	Attributes struct {
 		FileName string `json:"fileName"`
+		Name     string `json:"name"`
+		Username string `json:"username"`
 	} `json:"attributes"`
 }
 
+type mangaResponse struct {
+	Data struct {
+		ID         string `json:"id"`
+		Attributes struct {
+			Title            map[string]string   `json:"title"`
+			AltTitles        []map[string]string `json:"altTitles"`
+			Description      map[string]string   `json:"description"`
+			OriginalLanguage string              `json:"originalLanguage"`
+			Status           string              `json:"status"`
+			ContentRating    string              `json:"contentRating"`
+			Year             int                 `json:"year"`
+			LastVolume       string              `json:"lastVolume"`
+			LastChapter      string              `json:"lastChapter"`
+			Links            map[string]string   `json:"links"`
+			Tags             []struct {
+				Attributes struct {
+					Name  map[string]string `json:"name"`
+					Group string            `json:"group"`
+				} `json:"attributes"`
+			} `json:"tags"`
+		} `json:"attributes"`
+		Relationships []mangaRelationship `json:"relationships"`
+	} `json:"data"`
+}
+
 type mangaSearchResponse struct {
 	Data []struct {
 		ID         string `json:"id"`
@@ -208,21 +570,48 @@ type mangaSearchResponse struct {
 		} `json:"attributes"`
 		Relationships []mangaRelationship `json:"relationships"`
 	} `json:"data"`
+	Total int `json:"total"`
 }
 
 type chapterResponse struct {
 	Data []struct {
 		ID         string `json:"id"`
 		Attributes struct {
-			Volume      string `json:"volume"`
-			Chapter     string `json:"chapter"`
-			Title       string `json:"title"`
-			Pages       int    `json:"pages"`
-			ExternalURL string `json:"externalUrl"`
+			Volume             string    `json:"volume"`
+			Chapter            string    `json:"chapter"`
+			Title              string    `json:"title"`
+			TranslatedLanguage string    `json:"translatedLanguage"`
+			Pages              int       `json:"pages"`
+			ExternalURL        string    `json:"externalUrl"`
+			PublishAt          time.Time `json:"publishAt"`
+		} `json:"attributes"`
+		Relationships []mangaRelationship `json:"relationships"`
+	} `json:"data"`
+}
+
+type tagResponse struct {
+	Data []struct {
+		ID         string `json:"id"`
+		Attributes struct {
+			Name map[string]string `json:"name"`
 		} `json:"attributes"`
 	} `json:"data"`
 }
 
+type aggregateResponse struct {
+	Volumes json.RawMessage `json:"volumes"`
+}
+
+type aggregateVolume struct {
+	Chapters json.RawMessage `json:"chapters"`
+}
+
+type aggregateChapter struct {
+	Chapter string   `json:"chapter"`
+	ID      string   `json:"id"`
+	Others  []string `json:"others"`
+}
+
 type chapterDetails struct {
 	Result  string `json:"result"`
 	BaseURL string `json:"baseUrl"`
@@ -233,6 +622,86 @@ type chapterDetails struct {
 	} `json:"chapter"`
 }
 
+func decodeObject[T any](raw json.RawMessage) map[string]T {
+	values := map[string]T{}
+	if len(raw) == 0 || raw[0] != '{' {
+		return values
+	}
+	if err := json.Unmarshal(raw, &values); err != nil {
+		return map[string]T{}
+	}
+	return values
+}
+
+func chapterKey(chapter manga.Chapter) string {
+	if chapter.Number == "" {
+		return "none"
+	}
+	return chapter.Number
+}
+
+func preferLanguages(chapters []manga.Chapter, languages []string) []manga.Chapter {
+	rank := map[string]int{}
+	for index, language := range languages {
+		rank[language] = index
+	}
+	best := map[string]int{}
+	for _, chapter := range chapters {
+		value, ok := rank[strings.ToLower(chapter.Language)]
+		if !ok {
+			continue
+		}
+		if current, seen := best[chapterKey(chapter)]; !seen || value < current {
+			best[chapterKey(chapter)] = value
+		}
+	}
+
+	preferred := []manga.Chapter{}
+	for _, chapter := range chapters {
+		value, ok := rank[strings.ToLower(chapter.Language)]
+		if ok && value == best[chapterKey(chapter)] {
+			preferred = append(preferred, chapter)
+		}
+	}
+	return preferred
+}
+
+func fallbackLanguages(chapters []manga.Chapter) []string {
+	languages := []string{}
+	seen := map[string]bool{}
+	for _, chapter := range chapters {
+		language := strings.ToLower(chapter.Language)
+		if !seen[language] {
+			seen[language] = true
+			languages = append(languages, language)
+		}
+	}
+	sort.Slice(languages, func(i, j int) bool {
+		if (languages[i] == manga.DefaultLanguage) != (languages[j] == manga.DefaultLanguage) {
+			return languages[i] == manga.DefaultLanguage
+		}
+		return languages[i] < languages[j]
+	})
+	return languages
+}
+
+func relationshipNames(relationships []mangaRelationship, kind string) string {
+	names := []string{}
+	for _, relationship := range relationships {
+		if relationship.Type != kind {
+			continue
+		}
+		name := relationship.Attributes.Name
+		if kind == "user" {
+			name = relationship.Attributes.Username
+		}
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, " & ")
+}
+
 func buildCoverURL(mangaID, fileName string) string {
 	if mangaID == "" || fileName == "" {
 		return ""
@@ -254,6 +723,57 @@ func pickCoverFileName(relationships []mangaRelationship) string {
 	return ""
 }
 
+var externalLinks = []struct {
+	key    string
+	name   string
+	format string
+}{
+	{key: "al", name: "AniList", format: "https://anilist.co/manga/%s"},
+	{key: "mal", name: "MyAnimeList", format: "https://myanimelist.net/manga/%s"},
+	{key: "kt", name: "Kitsu", format: "https://kitsu.app/manga/%s"},
+	{key: "mu", name: "MangaUpdates", format: "https://www.mangaupdates.com/series/%s"},
+	{key: "ap", name: "Anime-Planet", format: "https://www.anime-planet.com/manga/%s"},
+	{key: "nu", name: "NovelUpdates", format: "https://www.novelupdates.com/series/%s"},
+	{key: "bw", name: "BookWalker", format: "https://bookwalker.jp/%s"},
+	{key: "engtl", name: "Official English", format: "%s"},
+	{key: "raw", name: "Official raw", format: "%s"},
+	{key: "amz", name: "Amazon", format: "%s"},
+	{key: "ebj", name: "eBookJapan", format: "%s"},
+	{key: "cdj", name: "CDJapan", format: "%s"},
+}
+
+func seriesLinks(mangaID string, links map[string]string) []manga.Link {
+	result := []manga.Link{{Name: "MangaDex", URL: "https://mangadex.org/title/" + mangaID}}
+	for _, link := range externalLinks {
+		value := strings.TrimSpace(links[link.key])
+		if value == "" {
+			continue
+		}
+		format := link.format
+		if _, err := strconv.Atoi(value); err == nil && link.key == "mu" {
+			format = "https://www.mangaupdates.com/series.html?id=%s"
+		}
+		result = append(result, manga.Link{Name: link.name, URL: fmt.Sprintf(format, value)})
+	}
+	return result
+}
+
+func altTitles(title string, titles []map[string]string) []string {
+	result := []string{}
+	seen := map[string]bool{strings.ToLower(title): true}
+	for _, entry := range titles {
+		for _, value := range entry {
+			value = strings.TrimSpace(value)
+			if value == "" || seen[strings.ToLower(value)] {
+				continue
+			}
+			seen[strings.ToLower(value)] = true
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
 func pickTitle(titles map[string]string) string {
 	if titles == nil {
 		return ""
@@ -285,7 +805,9 @@ func (provider *Provider) fetchChapterDetails(ctx context.Context, chapterID str
 		if err != nil {
 			lastErr = fmt.Errorf("error fetching chapter details: %w", err)
 			if attempt < 3 {
-				waitWithBackoff(ctx, attempt)
+				if err := waitWithBackoff(ctx, attempt); err != nil {
+					return nil, err
+				}
 				continue
 			}
 			return nil, lastErr
@@ -296,7 +818,9 @@ func (provider *Provider) fetchChapterDetails(ctx context.Context, chapterID str
 		if err != nil {
 			lastErr = fmt.Errorf("error reading chapter details response: %w", err)
 			if attempt < 3 {
-				waitWithBackoff(ctx, attempt)
+				if err := waitWithBackoff(ctx, attempt); err != nil {
+					return nil, err
+				}
 				continue
 			}
 			return nil, lastErr
@@ -305,7 +829,9 @@ func (provider *Provider) fetchChapterDetails(ctx context.Context, chapterID str
 		if response.StatusCode != http.StatusOK {
 			lastErr = fmt.Errorf("chapter details request failed: %s", strings.TrimSpace(string(body)))
 			if shouldRetry(response.StatusCode) && attempt < 3 {
-				waitWithBackoff(ctx, attempt)
+				if err := waitWithBackoff(ctx, attempt); err != nil {
+					return nil, err
+				}
 				continue
 			}
 			return nil, lastErr
@@ -315,7 +841,9 @@ func (provider *Provider) fetchChapterDetails(ctx context.Context, chapterID str
 		if err := json.Unmarshal(body, &details); err != nil {
 			lastErr = fmt.Errorf("error parsing chapter details: %w", err)
 			if attempt < 3 {
-				waitWithBackoff(ctx, attempt)
+				if err := waitWithBackoff(ctx, attempt); err != nil {
+					return nil, err
+				}
 				continue
 			}
 			return nil, lastErr
@@ -324,7 +852,9 @@ func (provider *Provider) fetchChapterDetails(ctx context.Context, chapterID str
 		if details.Result != "ok" {
 			lastErr = fmt.Errorf("chapter details request returned %q", details.Result)
 			if attempt < 3 {
-				waitWithBackoff(ctx, attempt)
+				if err := waitWithBackoff(ctx, attempt); err != nil {
+					return nil, err
+				}
 				continue
 			}
 			return nil, lastErr
@@ -342,7 +872,9 @@ func (provider *Provider) fetchChapterDetails(ctx context.Context, chapterID str
 				len(details.Chapter.DataSaver),
 			)
 			if attempt < 3 {
-				waitWithBackoff(ctx, attempt)
+				if err := waitWithBackoff(ctx, attempt); err != nil {
+					return nil, err
+				}
 				continue
 			}
 			return nil, lastErr
@@ -357,71 +889,346 @@ func (provider *Provider) fetchChapterDetails(ctx context.Context, chapterID str
 	return nil, lastErr
 }
 
-func (provider *Provider) downloadChapterImages(ctx context.Context, chapter *chapterDetails) ([][]byte, error) {
+type pageResult struct {
+	index int
+	data  []byte
+	err   error
+}
+
+type pageSource struct {
+	baseURL     string
+	pathSegment string
+	hash        string
+	fileNames   []string
+}
+
+type deliveryReport struct {
+	URL      string `json:"url"`
+	Success  bool   `json:"success"`
+	Bytes    int    `json:"bytes"`
+	Duration int64  `json:"duration"`
+	Cached   bool   `json:"cached"`
+}
+
+func newPageSource(chapter *chapterDetails, preferDataSaver bool) (pageSource, error) {
 	if chapter.BaseURL == "" || chapter.Chapter.Hash == "" {
-		return nil, fmt.Errorf("%w: invalid chapter details for download (baseUrl=%q hash=%q)", manga.ErrChapterMetadataMissing, chapter.BaseURL, chapter.Chapter.Hash)
+		return pageSource{}, fmt.Errorf("%w: invalid chapter details for download (baseUrl=%q hash=%q)", manga.ErrChapterMetadataMissing, chapter.BaseURL, chapter.Chapter.Hash)
+	}
+
+	source := pageSource{
+		baseURL:     chapter.BaseURL,
+		pathSegment: "data",
+		hash:        chapter.Chapter.Hash,
+		fileNames:   chapter.Chapter.Data,
+	}
+
+	if (preferDataSaver || len(source.fileNames) == 0) && len(chapter.Chapter.DataSaver) > 0 {
+		source.fileNames = chapter.Chapter.DataSaver
+		source.pathSegment = "data-saver"
+	}
+
+	if len(source.fileNames) == 0 {
+		return pageSource{}, fmt.Errorf("%w: no pages returned for chapter %s (data=%d dataSaver=%d)", manga.ErrChapterNoPages, source.hash, len(chapter.Chapter.Data), len(chapter.Chapter.DataSaver))
+	}
+
+	return source, nil
+}
+
+func (source pageSource) pageURL(index int) string {
+	return fmt.Sprintf("%s/%s/%s/%s", source.baseURL, source.pathSegment, source.hash, source.fileNames[index])
+}
+
+func (provider *Provider) downloadChapterImages(ctx context.Context, chapterID string, chapter *chapterDetails, progress manga.PageProgress) ([][]byte, error) {
+	source, err := newPageSource(chapter, false)
+	if err != nil {
+		return nil, err
+	}
+
+	images := make([][]byte, len(source.fileNames))
+	completed := 0
+	onPage := func() {
+		completed++
+		if progress != nil {
+			progress(completed, len(images))
+		}
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= nodeAttempts; attempt++ {
+		lastErr = provider.downloadPages(ctx, source, images, onPage)
+		if lastErr == nil {
+			return images, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if attempt == nodeAttempts || chapterID == "" {
+			break
+		}
+
+		next, refreshed := provider.failoverSource(ctx, chapterID, chapter, source)
+		if next.pathSegment != source.pathSegment {
+			for index := range images {
+				images[index] = nil
+			}
+			completed = 0
+		}
+		source, chapter = next, refreshed
 	}
 
-	baseURL := chapter.BaseURL
-	hash := chapter.Chapter.Hash
-	fileNames := chapter.Chapter.Data
-	pathSegment := "data"
+	return nil, lastErr
+}
 
-	if len(fileNames) == 0 && len(chapter.Chapter.DataSaver) > 0 {
-		fileNames = chapter.Chapter.DataSaver
-		pathSegment = "data-saver"
+func (provider *Provider) failoverSource(ctx context.Context, chapterID string, chapter *chapterDetails, current pageSource) (pageSource, *chapterDetails) {
+	if refreshed, err := provider.fetchChapterDetails(ctx, chapterID); err == nil && refreshed.Chapter.Hash == chapter.Chapter.Hash {
+		chapter = refreshed
 	}
 
-	if len(fileNames) == 0 {
-		return nil, fmt.Errorf("%w: no pages returned for chapter %s (data=%d dataSaver=%d)", manga.ErrChapterNoPages, hash, len(chapter.Chapter.Data), len(chapter.Chapter.DataSaver))
+	preferDataSaver := current.pathSegment == "data-saver" || chapter.BaseURL == current.baseURL
+	next, err := newPageSource(chapter, preferDataSaver)
+	if err != nil || len(next.fileNames) != len(current.fileNames) {
+		current.baseURL = chapter.BaseURL
+		return current, chapter
 	}
 
-	images := make([][]byte, len(fileNames))
+	return next, chapter
+}
 
-	for i, fileName := range fileNames {
-		endpoint := fmt.Sprintf("%s/%s/%s/%s", baseURL, pathSegment, hash, fileName)
+func (provider *Provider) downloadPages(ctx context.Context, source pageSource, images [][]byte, onPage func()) error {
+	pending := []int{}
+	for index := range images {
+		if images[index] == nil {
+			pending = append(pending, index)
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan int)
+	results := make(chan pageResult)
+
+	var workers sync.WaitGroup
+	for worker := 0; worker < min(pageWorkers, len(pending)); worker++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for index := range jobs {
+				data, err := provider.downloadPage(ctx, source.pageURL(index), source.fileNames[index])
+				select {
+				case results <- pageResult{index: index, data: data, err: err}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for _, index := range pending {
+			select {
+			case jobs <- index:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	go func() {
+		workers.Wait()
+		close(results)
+	}()
+
+	var firstErr error
+	for result := range results {
+		if result.err != nil {
+			if firstErr == nil {
+				firstErr = result.err
+				cancel()
+			}
+			continue
+		}
+		images[result.index] = result.data
+		onPage()
+	}
+
+	if firstErr != nil {
+		return firstErr
+	}
+	for _, index := range pending {
+		if images[index] == nil {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			return fmt.Errorf("page %s was not downloaded", source.fileNames[index])
+		}
+	}
+
+	return nil
+}
+
+func (provider *Provider) downloadPage(ctx context.Context, endpoint, fileName string) ([]byte, error) {
+	var lastErr error
+	for attempt := 1; attempt <= pageAttempts; attempt++ {
 		request, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
 		if err != nil {
 			return nil, fmt.Errorf("error building chapter request: %w", err)
 		}
 		provider.addHeaders(request)
 
+		started := time.Now()
 		response, err := provider.httpClient.Do(request)
 		if err != nil {
-			return nil, fmt.Errorf("error downloading %s: %w", fileName, err)
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			provider.reportDelivery(deliveryReport{URL: endpoint, Duration: time.Since(started).Milliseconds()})
+			lastErr = fmt.Errorf("error downloading %s: %w", fileName, err)
+			if attempt < pageAttempts {
+				if err := waitWithBackoff(ctx, attempt); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			return nil, lastErr
 		}
 
 		imgData, err := io.ReadAll(response.Body)
 		response.Body.Close()
+		var imageErr error
+		if err == nil && response.StatusCode == http.StatusOK && len(imgData) > 0 {
+			_, imageErr = imaging.Validate(imgData)
+		}
+		report := deliveryReport{
+			URL:      endpoint,
+			Success:  err == nil && response.StatusCode == http.StatusOK && len(imgData) > 0 && imageErr == nil,
+			Bytes:    len(imgData),
+			Duration: time.Since(started).Milliseconds(),
+			Cached:   strings.HasPrefix(response.Header.Get("X-Cache"), "HIT"),
+		}
+		if ctx.Err() == nil {
+			provider.reportDelivery(report)
+		}
+
 		if err != nil {
-			return nil, fmt.Errorf("error reading %s: %w", fileName, err)
+			lastErr = fmt.Errorf("error reading %s: %w", fileName, err)
+			if attempt < pageAttempts {
+				if err := waitWithBackoff(ctx, attempt); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			return nil, lastErr
+		}
+
+		if response.StatusCode != http.StatusOK {
+			lastErr = fmt.Errorf("downloading %s failed: %s", fileName, response.Status)
+			if shouldRetry(response.StatusCode) && attempt < pageAttempts {
+				if err := waitWithBackoff(ctx, attempt); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			return nil, lastErr
 		}
 
 		if len(imgData) == 0 {
-			return nil, fmt.Errorf("downloaded image %s is empty", fileName)
+			lastErr = fmt.Errorf("downloaded image %s is empty", fileName)
+			if attempt < pageAttempts {
+				if err := waitWithBackoff(ctx, attempt); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			return nil, lastErr
+		}
+
+		if imageErr != nil {
+			lastErr = fmt.Errorf("downloaded image %s is invalid: %w", fileName, imageErr)
+			if attempt < pageAttempts {
+				if err := waitWithBackoff(ctx, attempt); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			return nil, lastErr
 		}
 
-		images[i] = imgData
+		return imgData, nil
+	}
+
+	if lastErr == nil {
+		lastErr = fmt.Errorf("unable to download %s", fileName)
+	}
+	return nil, lastErr
+}
+
+func (provider *Provider) reportDelivery(report deliveryReport) {
+	if provider.reportURL == "" {
+		return
+	}
+	if parsed, err := url.Parse(report.URL); err != nil || strings.HasSuffix(parsed.Hostname(), "mangadex.org") {
+		return
+	}
+
+	provider.reportOnce.Do(func() {
+		go func() {
+			for report := range provider.reports {
+				provider.sendReport(report)
+			}
+		}()
+	})
+	select {
+	case provider.reports <- report:
+	default:
+	}
+}
+
+func (provider *Provider) sendReport(report deliveryReport) {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), reportTimeout)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, provider.reportURL, bytes.NewReader(body))
+	if err != nil {
+		return
 	}
+	request.Header.Set("User-Agent", mangaDexUserAgent)
+	request.Header.Set("Content-Type", "application/json")
 
-	return images, nil
+	response, err := provider.httpClient.Do(request)
+	if err != nil {
+		return
+	}
+	io.Copy(io.Discard, response.Body)
+	response.Body.Close()
 }
 
 func shouldRetry(statusCode int) bool {
 	return statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError
 }
 
-func waitWithBackoff(ctx context.Context, attempt int) {
+func waitWithBackoff(ctx context.Context, attempt int) error {
 	wait := time.Duration(attempt*attempt) * 250 * time.Millisecond
 	if deadline, ok := ctx.Deadline(); ok {
 		if time.Until(deadline) < wait {
-			return
+			return ctx.Err()
 		}
 	}
 	timer := time.NewTimer(wait)
 	defer timer.Stop()
 	select {
 	case <-ctx.Done():
+		return ctx.Err()
 	case <-timer.C:
+		return nil
 	}
 }
@@ -0,0 +1,538 @@
+package mangadex
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ssh-vom/boox-serve/internal/providers/manga"
+)
+
+func TestDownloadChapterImagesPreservesOrderAndRetries(t *testing.T) {
+	var mu sync.Mutex
+	attempts := map[string]int{}
+	var reports []deliveryReport
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/report" {
+			var report deliveryReport
+			if err := json.NewDecoder(r.Body).Decode(&report); err != nil {
+				t.Errorf("invalid report payload: %v", err)
+			}
+			mu.Lock()
+			reports = append(reports, report)
+			mu.Unlock()
+			return
+		}
+
+		name := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		mu.Lock()
+		attempts[name]++
+		count := attempts[name]
+		mu.Unlock()
+
+		if name == "p3.jpg" && count == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write(testPage(t, name))
+	}))
+	defer server.Close()
+
+	details := &chapterDetails{BaseURL: server.URL}
+	details.Chapter.Hash = "hash"
+	for i := 1; i <= 10; i++ {
+		details.Chapter.Data = append(details.Chapter.Data, fmt.Sprintf("p%d.jpg", i))
+	}
+
+	var progressCalls int
+	provider := New(server.Client(), "")
+	provider.reportURL = server.URL + "/report"
+	images, err := provider.downloadChapterImages(context.Background(), "", details, func(completed, total int) {
+		progressCalls++
+		if total != 10 {
+			t.Errorf("unexpected total %d", total)
+		}
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	for i, image := range images {
+		if want := testPage(t, fmt.Sprintf("p%d.jpg", i+1)); !bytes.Equal(image, want) {
+			t.Fatalf("page %d: unexpected image data", i)
+		}
+	}
+	if progressCalls != 10 {
+		t.Fatalf("expected 10 progress calls, got %d", progressCalls)
+	}
+	if attempts["p3.jpg"] != 2 {
+		t.Fatalf("expected p3.jpg to be retried once, got %d attempts", attempts["p3.jpg"])
+	}
+
+	delivered := waitForReports(t, &mu, &reports, 11)
+	failures := 0
+	for _, report := range delivered {
+		if !report.Success {
+			failures++
+		}
+	}
+	if len(delivered) != 11 || failures != 1 {
+		t.Fatalf("expected 11 reports with 1 failure, got %d reports with %d failures", len(delivered), failures)
+	}
+}
+
+func TestDownloadChapterImagesFailsOnPersistentError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "p2.jpg") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(testPage(t, "ok"))
+	}))
+	defer server.Close()
+
+	details := &chapterDetails{BaseURL: server.URL}
+	details.Chapter.Hash = "hash"
+	details.Chapter.Data = []string{"p1.jpg", "p2.jpg", "p3.jpg"}
+
+	provider := New(server.Client(), "")
+	provider.reportURL = ""
+	if _, err := provider.downloadChapterImages(context.Background(), "", details, nil); err == nil {
+		t.Fatalf("expected error for missing page")
+	}
+}
+
+func TestDownloadChapterImagesRetriesCorruptPages(t *testing.T) {
+	var mu sync.Mutex
+	attempts := map[string]int{}
+	var reports []deliveryReport
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/report" {
+			var report deliveryReport
+			if err := json.NewDecoder(r.Body).Decode(&report); err != nil {
+				t.Errorf("invalid report payload: %v", err)
+			}
+			mu.Lock()
+			reports = append(reports, report)
+			mu.Unlock()
+			return
+		}
+
+		name := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		mu.Lock()
+		attempts[name]++
+		count := attempts[name]
+		mu.Unlock()
+
+		page := testPage(t, name)
+		if name == "p2.jpg" && count == 1 {
+			page = page[:len(page)/2]
+		}
+		w.Write(page)
+	}))
+	defer server.Close()
+
+	details := &chapterDetails{BaseURL: server.URL}
+	details.Chapter.Hash = "hash"
+	details.Chapter.Data = []string{"p1.jpg", "p2.jpg"}
+
+	provider := New(server.Client(), "")
+	provider.reportURL = server.URL + "/report"
+	images, err := provider.downloadChapterImages(context.Background(), "", details, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !bytes.Equal(images[1], testPage(t, "p2.jpg")) {
+		t.Fatalf("expected the retried page to be kept")
+	}
+	if attempts["p2.jpg"] != 2 {
+		t.Fatalf("expected truncated page to be retried once, got %d attempts", attempts["p2.jpg"])
+	}
+
+	delivered := waitForReports(t, &mu, &reports, 3)
+	failures := 0
+	for _, report := range delivered {
+		if !report.Success {
+			failures++
+		}
+	}
+	if failures != 1 {
+		t.Fatalf("expected the truncated page to be reported as a failure, got %d failures", failures)
+	}
+}
+
+func TestDownloadChapterImagesKeepsOneQualityAfterFailover(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/at-home/server/chapter-1":
+			fmt.Fprintf(w, `{"result":"ok","baseUrl":%q,"chapter":{"hash":"hash","data":["p1.png","p2.png"],"dataSaver":["p1.jpg","p2.jpg"]}}`, server.URL)
+		case r.URL.Path == "/data/hash/p2.png":
+			w.WriteHeader(http.StatusNotFound)
+		case strings.HasPrefix(r.URL.Path, "/data"):
+			w.Write(testPage(t, r.URL.Path))
+		}
+	}))
+	defer server.Close()
+
+	details := &chapterDetails{BaseURL: server.URL}
+	details.Chapter.Hash = "hash"
+	details.Chapter.Data = []string{"p1.png", "p2.png"}
+	details.Chapter.DataSaver = []string{"p1.jpg", "p2.jpg"}
+
+	target, _ := url.Parse(server.URL)
+	provider := New(&http.Client{Transport: rewriteTransport{target: target}}, "")
+	provider.reportURL = ""
+	completed := 0
+	images, err := provider.downloadChapterImages(context.Background(), "chapter-1", details, func(done, total int) {
+		completed = done
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for index, name := range []string{"/data-saver/hash/p1.jpg", "/data-saver/hash/p2.jpg"} {
+		if !bytes.Equal(images[index], testPage(t, name)) {
+			t.Fatalf("page %d: expected %s after failover", index, name)
+		}
+	}
+	if completed != 2 {
+		t.Fatalf("expected progress to restart with the new source, got %d", completed)
+	}
+}
+
+func TestFailoverSourceSwitchesToDataSaverOnSameNode(t *testing.T) {
+	details := &chapterDetails{BaseURL: "https://node.example"}
+	details.Chapter.Hash = "hash"
+	details.Chapter.Data = []string{"a.png", "b.png"}
+	details.Chapter.DataSaver = []string{"a.jpg", "b.jpg"}
+
+	current, err := newPageSource(details, false)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	provider := New(nil, "")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	next, _ := provider.failoverSource(ctx, "chapter", details, current)
+	if next.pathSegment != "data-saver" || next.fileNames[0] != "a.jpg" {
+		t.Fatalf("expected data-saver source, got %+v", next)
+	}
+}
+
+type rewriteTransport struct {
+	target *url.URL
+}
+
+func (transport rewriteTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	rewritten := request.Clone(request.Context())
+	rewritten.URL.Scheme = transport.target.Scheme
+	rewritten.URL.Host = transport.target.Host
+	return http.DefaultTransport.RoundTrip(rewritten)
+}
+
+func newTestProvider(t *testing.T, handler http.HandlerFunc) *Provider {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	target, _ := url.Parse(server.URL)
+	return New(&http.Client{Transport: rewriteTransport{target: target}}, "")
+}
+
+func TestFetchSeriesParsesMetadata(t *testing.T) {
+	provider := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/manga/manga-1" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		w.Write([]byte(`{"data":{"id":"manga-1","attributes":{
+			"title":{"en":"Series"},
+			"altTitles":[{"ja-ro":"Shiriizu"},{"en":"series"},{"ja":"シリーズ"}],
+			"description":{"en":"A summary "},
+			"originalLanguage":"ja","status":"ongoing","contentRating":"safe","year":2004,
+			"lastVolume":"12","lastChapter":"104",
+			"links":{"al":"30013","mu":"54","raw":"https://example.com/raw"},
+			"tags":[
+				{"attributes":{"name":{"en":"Action"},"group":"genre"}},
+				{"attributes":{"name":{"en":"Monsters"},"group":"theme"}}
+			]},
+			"relationships":[
+				{"id":"a1","type":"author","attributes":{"name":"Author"}},
+				{"id":"a2","type":"artist","attributes":{"name":"Artist"}},
+				{"id":"c1","type":"cover_art","attributes":{"fileName":"cover.jpg"}}
+			]}}`))
+	})
+
+	series, err := provider.FetchSeries(context.Background(), "manga-1")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if series.Title != "Series" || series.Description != "A summary" || series.Year != 2004 || series.Language != "ja" {
+		t.Fatalf("unexpected series: %+v", series)
+	}
+	if len(series.Authors) != 1 || series.Authors[0] != "Author" || len(series.Artists) != 1 || series.Artists[0] != "Artist" {
+		t.Fatalf("unexpected people: %+v", series)
+	}
+	if len(series.Genres) != 1 || series.Genres[0] != "Action" || len(series.Tags) != 1 || series.Tags[0] != "Monsters" {
+		t.Fatalf("unexpected tags: %+v", series)
+	}
+	if series.CoverURL != "https://uploads.mangadex.org/covers/manga-1/cover.jpg.256.jpg" {
+		t.Fatalf("unexpected cover url %q", series.CoverURL)
+	}
+
+	details, err := provider.FetchDetails(context.Background(), "manga-1")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if details.Title != "Series" || len(details.Authors) != 1 {
+		t.Fatalf("expected details to include the series, got %+v", details.Series)
+	}
+	if strings.Join(details.AltTitles, ",") != "Shiriizu,シリーズ" || details.LastVolume != "12" || details.LastChapter != "104" {
+		t.Fatalf("unexpected details: %+v", details)
+	}
+
+	links := []string{}
+	for _, link := range details.Links {
+		links = append(links, link.Name+"="+link.URL)
+	}
+	want := "MangaDex=https://mangadex.org/title/manga-1,AniList=https://anilist.co/manga/30013,MangaUpdates=https://www.mangaupdates.com/series.html?id=54,Official raw=https://example.com/raw"
+	if strings.Join(links, ",") != want {
+		t.Fatalf("unexpected links %v", links)
+	}
+}
+
+func TestSearchTranslatesFilter(t *testing.T) {
+	tagRequests := 0
+	provider := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/manga/tag":
+			tagRequests++
+			w.Write([]byte(`{"data":[
+				{"id":"tag-action","attributes":{"name":{"en":"Action"}}},
+				{"id":"tag-horror","attributes":{"name":{"en":"Horror"}}}
+			]}`))
+		case "/manga":
+			query := r.URL.Query()
+			checks := map[string]string{
+				"title":                    "",
+				"contentRating[]":          "safe,suggestive",
+				"status[]":                 "completed",
+				"publicationDemographic[]": "seinen",
+				"originalLanguage[]":       "ja",
+				"includedTags[]":           "tag-action",
+				"excludedTags[]":           "tag-horror",
+				"year":                     "2004",
+				"order[followedCount]":     "desc",
+			}
+			for key, want := range checks {
+				if got := strings.Join(query[key], ","); got != want {
+					t.Errorf("%s = %q, want %q", key, got, want)
+				}
+			}
+			w.Write([]byte(`{"data":[{"id":"manga-1","attributes":{"title":{"en":"Series"}}}]}`))
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	}).WithContentRatings([]string{"safe", "suggestive"})
+
+	filter := manga.SearchFilter{
+		Statuses:          []string{"completed"},
+		Demographics:      []string{"seinen"},
+		OriginalLanguages: []string{"ja"},
+		IncludedTags:      []string{"action"},
+		ExcludedTags:      []string{"HORROR"},
+		Year:              2004,
+		Order:             manga.OrderPopular,
+	}
+	for attempt := 0; attempt < 2; attempt++ {
+		page, err := provider.Search(context.Background(), "", filter, 0)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(page.Results) != 1 || page.Results[0].Title != "Series" {
+			t.Fatalf("unexpected results %+v", page.Results)
+		}
+	}
+	if tagRequests != 1 {
+		t.Fatalf("expected tags to be fetched once, got %d", tagRequests)
+	}
+
+	if _, err := provider.Search(context.Background(), "x", manga.SearchFilter{IncludedTags: []string{"missing"}}, 0); err == nil {
+		t.Fatalf("expected error for unknown tag")
+	}
+	if _, err := provider.Search(context.Background(), "x", manga.SearchFilter{Statuses: []string{"paused"}}, 0); err == nil {
+		t.Fatalf("expected error for unknown status")
+	}
+}
+
+func TestSearchPagesThroughResults(t *testing.T) {
+	provider := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("limit") != "20" {
+			t.Errorf("unexpected limit %q", query.Get("limit"))
+		}
+		switch query.Get("offset") {
+		case "0":
+			w.Write([]byte(`{"data":[{"id":"m1","attributes":{"title":{"en":"One"}}},{"id":"m2","attributes":{"title":{}}}],"total":3}`))
+		case "2":
+			w.Write([]byte(`{"data":[{"id":"m3","attributes":{"title":{"en":"Three"}}}],"total":3}`))
+		default:
+			t.Errorf("unexpected offset %q", query.Get("offset"))
+		}
+	})
+
+	first, err := provider.Search(context.Background(), "one", manga.SearchFilter{}, 0)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(first.Results) != 1 || first.Next != 2 || first.Total != 3 || !first.HasMore() {
+		t.Fatalf("unexpected first page %+v", first)
+	}
+
+	second, err := provider.Search(context.Background(), "one", manga.SearchFilter{}, first.Next)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(second.Results) != 1 || second.Results[0].ID != "m3" || second.HasMore() {
+		t.Fatalf("unexpected second page %+v", second)
+	}
+
+	last, err := provider.Search(context.Background(), "one", manga.SearchFilter{}, 10000)
+	if err != nil || len(last.Results) != 0 || last.HasMore() {
+		t.Fatalf("expected empty page past the search window, got %+v, %v", last, err)
+	}
+}
+
+func TestFetchChaptersPrefersLanguagesAndFallsBack(t *testing.T) {
+	provider := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		switch {
+		case r.URL.Path == "/manga/manga-1/aggregate":
+			w.Write([]byte(`{"result":"ok","volumes":{"1":{"volume":"1","chapters":{
+				"1":{"chapter":"1","id":"es-1","others":["en-1","fr-1"]},
+				"2":{"chapter":"2","id":"en-2","others":["fr-2"]},
+				"3":{"chapter":"3","id":"fr-3","others":[]}
+			}}}}`))
+		case r.URL.Path == "/chapter" && query.Get("manga") == "manga-1":
+			languages := strings.Join(query["translatedLanguage[]"], ",")
+			if languages != "es,en" {
+				t.Errorf("unexpected languages %q", languages)
+			}
+			w.Write([]byte(`{"data":[
+				{"id":"es-1","attributes":{"chapter":"1","translatedLanguage":"es","pages":10}},
+				{"id":"en-1","attributes":{"chapter":"1","translatedLanguage":"en","pages":10}},
+				{"id":"en-2","attributes":{"chapter":"2","translatedLanguage":"en","pages":10}}
+			]}`))
+		case r.URL.Path == "/chapter":
+			ids := strings.Join(query["ids[]"], ",")
+			if ids != "fr-3" {
+				t.Errorf("unexpected fallback ids %q", ids)
+			}
+			w.Write([]byte(`{"data":[{"id":"fr-3","attributes":{"chapter":"3","translatedLanguage":"fr","pages":10}}]}`))
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	}).WithLanguages([]string{"ES", "en", "es"})
+
+	chapters, err := provider.FetchChapters(context.Background(), "manga-1")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	got := []string{}
+	for _, chapter := range chapters {
+		got = append(got, chapter.ID+":"+chapter.Language)
+	}
+	if strings.Join(got, ",") != "es-1:es,en-2:en,fr-3:fr" {
+		t.Fatalf("unexpected chapters %v", got)
+	}
+}
+
+func TestFetchChaptersIgnoresAggregateFailure(t *testing.T) {
+	provider := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/manga/manga-1/aggregate" {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"data":[{"id":"en-1","attributes":{"chapter":"1","translatedLanguage":"en","pages":10}}]}`))
+	})
+
+	chapters, err := provider.FetchChapters(context.Background(), "manga-1")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(chapters) != 1 || chapters[0].ID != "en-1" {
+		t.Fatalf("unexpected chapters %+v", chapters)
+	}
+}
+
+func TestFetchChaptersParsesGroupsAndUploader(t *testing.T) {
+	provider := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/manga/manga-1/aggregate" {
+			w.Write([]byte(`{"result":"ok","volumes":{}}`))
+			return
+		}
+		if includes := strings.Join(r.URL.Query()["includes[]"], ","); includes != "scanlation_group,user" {
+			t.Errorf("unexpected includes %q", includes)
+		}
+		w.Write([]byte(`{"data":[{"id":"en-1","attributes":{"chapter":"1","translatedLanguage":"en","pages":24,"publishAt":"2024-03-01T12:00:00+00:00"},
+			"relationships":[
+				{"id":"g1","type":"scanlation_group","attributes":{"name":"Alpha Scans"}},
+				{"id":"g2","type":"scanlation_group","attributes":{"name":"Beta"}},
+				{"id":"u1","type":"user","attributes":{"username":"uploader"}}
+			]}]}`))
+	})
+
+	chapters, err := provider.FetchChapters(context.Background(), "manga-1")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(chapters) != 1 {
+		t.Fatalf("unexpected chapters %+v", chapters)
+	}
+	chapter := chapters[0]
+	if chapter.Group != "Alpha Scans & Beta" || chapter.Uploader != "uploader" || chapter.Pages != 24 {
+		t.Fatalf("unexpected chapter metadata %+v", chapter)
+	}
+	if want := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC); !chapter.PublishedAt.Equal(want) {
+		t.Fatalf("expected published at %v, got %v", want, chapter.PublishedAt)
+	}
+}
+
+func waitForReports(t *testing.T, mu *sync.Mutex, reports *[]deliveryReport, count int) []deliveryReport {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		mu.Lock()
+		got := append([]deliveryReport(nil), *reports...)
+		mu.Unlock()
+		if len(got) >= count || time.Now().After(deadline) {
+			return got
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func testPage(t *testing.T, name string) []byte {
+	t.Helper()
+	page := image.NewGray(image.Rect(0, 0, len(name), 1))
+	copy(page.Pix, name)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, page); err != nil {
+		t.Fatalf("unable to encode test page: %v", err)
+	}
+	return buf.Bytes()
+}
@@ -2,9 +2,13 @@ package manga
 
 import "context"
 
+type PageProgress func(completed, total int)
+
 type Provider interface {
-	Search(ctx context.Context, query string) ([]SearchResult, error)
+	Search(ctx context.Context, query string, filter SearchFilter, offset int) (SearchPage, error)
+	FetchSeries(ctx context.Context, mangaID string) (Series, error)
+	FetchDetails(ctx context.Context, mangaID string) (SeriesDetails, error)
 	FetchChapters(ctx context.Context, mangaID string) ([]Chapter, error)
-	DownloadChapterImages(ctx context.Context, chapter Chapter) ([][]byte, error)
+	DownloadChapterImages(ctx context.Context, chapter Chapter, progress PageProgress) ([][]byte, error)
 	FetchCover(ctx context.Context, coverURL string) ([]byte, error)
 }
@@ -3,6 +3,7 @@ package manga
 import (
 	"errors"
 	"fmt"
+	"time"
 )
 
 type SearchResult struct {
@@ -11,12 +12,59 @@ type SearchResult struct {
 	CoverURL string
 }
 
+type SearchPage struct {
+	Results []SearchResult
+	Total   int
+	Next    int
+}
+
+func (page SearchPage) HasMore() bool {
+	return page.Next < page.Total
+}
+
+type Series struct {
+	ID            string
+	Title         string
+	Description   string
+	Authors       []string
+	Artists       []string
+	Genres        []string
+	Tags          []string
+	Language      string
+	Status        string
+	ContentRating string
+	Year          int
+	CoverURL      string
+}
+
+type SeriesDetails struct {
+	Series
+	AltTitles   []string
+	LastVolume  string
+	LastChapter string
+	Links       []Link
+}
+
+type Link struct {
+	Name string
+	URL  string
+}
+
+func (series Series) RightToLeft() bool {
+	return series.Language == "ja"
+}
+
 type Chapter struct {
-	ID             string
-	Number         string
-	Title          string
-	Volume         string
-	NumericChapter float64
+	ID             string    `json:"id"`
+	Number         string    `json:"number"`
+	Title          string    `json:"title,omitempty"`
+	Volume         string    `json:"volume,omitempty"`
+	Language       string    `json:"language,omitempty"`
+	Group          string    `json:"group,omitempty"`
+	Uploader       string    `json:"uploader,omitempty"`
+	PublishedAt    time.Time `json:"published_at,omitempty"`
+	Pages          int       `json:"pages,omitempty"`
+	NumericChapter float64   `json:"numeric_chapter"`
 }
 
 var (
@@ -0,0 +1,196 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/ssh-vom/boox-serve/internal/config"
+	"github.com/ssh-vom/boox-serve/internal/providers/manga"
+)
+
+const (
+	maxAltTitles         = 3
+	maxDescriptionLength = 600
+)
+
+type seriesDetailsMsg struct {
+	mangaID string
+	details manga.SeriesDetails
+	err     error
+}
+
+func fetchSeriesDetailsCmd(provider manga.Provider, mangaID string) tea.Cmd {
+	return func() tea.Msg {
+		if provider == nil {
+			return seriesDetailsMsg{mangaID: mangaID, err: errors.New("manga provider unavailable")}
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+		defer cancel()
+		details, err := provider.FetchDetails(ctx, mangaID)
+		return seriesDetailsMsg{mangaID: mangaID, details: details, err: err}
+	}
+}
+
+func (model *model) openMangaDetails(result manga.SearchResult) tea.Cmd {
+	model.selectedManga = result
+	model.seriesDetails = manga.SeriesDetails{}
+	model.detailsLoading = true
+	model.errorMessage = ""
+	model.state = stateMangaDetails
+	return tea.Batch(model.spinner.Tick, fetchSeriesDetailsCmd(model.mangaProvider, result.ID))
+}
+
+func (model *model) handleSeriesDetails(msg seriesDetailsMsg) {
+	if msg.mangaID != model.selectedManga.ID {
+		return
+	}
+	model.detailsLoading = false
+	if msg.err != nil {
+		model.errorMessage = msg.err.Error()
+		return
+	}
+	model.seriesDetails = msg.details
+}
+
+func (model *model) updateMangaDetails(msg tea.Msg) tea.Cmd {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		if !model.detailsLoading {
+			return nil
+		}
+		var cmd tea.Cmd
+		model.spinner, cmd = model.spinner.Update(msg)
+		return cmd
+	}
+
+	switch key.String() {
+	case "esc":
+		model.errorMessage = ""
+		model.state = stateMangaResults
+		return nil
+	case "enter":
+		model.errorMessage = ""
+		model.state = stateMangaLoadingChapters
+		return tea.Batch(model.spinner.Tick, fetchChaptersCmd(model.mangaProvider, model.selectedManga.ID))
+	case "f":
+		model.toggleFollow()
+		return nil
+	}
+	return nil
+}
+
+func (model *model) toggleFollow() {
+	followed := !model.config.Series[model.selectedManga.ID].Followed
+	model.updateSeries(func(series *config.SeriesConfig) {
+		series.Followed = followed
+	})
+}
+
+func (model model) mangaDetailsView() string {
+	panelWidth := coverPanelWidth(model.width)
+	textWidth := resultsListWidth(model.width)
+
+	lines := []string{titleStyle.Render(model.selectedManga.Title)}
+	followed := model.config.Series[model.selectedManga.ID].Followed
+	if followed {
+		lines = append(lines, focusedStyle.Render("★ Following"))
+	}
+	switch {
+	case model.detailsLoading:
+		lines = append(lines, model.spinner.View()+" Fetching details...")
+	case model.seriesDetails.ID != "":
+		lines = append(lines, seriesDetailLines(model.seriesDetails)...)
+	}
+	if model.errorMessage != "" {
+		lines = append(lines, warningStyle.Render(model.errorMessage))
+	}
+
+	followAction := "follow"
+	if followed {
+		followAction = "unfollow"
+	}
+	lines = append(lines, "", secondaryStyle.Render(fmt.Sprintf("Enter to choose chapters · f %s · esc to back", followAction)))
+
+	details := lipgloss.NewStyle().Width(textWidth).Render(lipgloss.JoinVertical(lipgloss.Left, lines...))
+	panel := model.mangaCoverPanel(model.selectedManga, panelWidth)
+	if model.width < 80 {
+		return lipgloss.JoinVertical(lipgloss.Left, details, panel)
+	}
+	return lipgloss.JoinHorizontal(lipgloss.Top, details, panel)
+}
+
+func seriesDetailLines(series manga.SeriesDetails) []string {
+	lines := []string{}
+	if len(series.AltTitles) > 0 {
+		titles := series.AltTitles
+		if len(titles) > maxAltTitles {
+			titles = append(titles[:maxAltTitles:maxAltTitles], fmt.Sprintf("+%d more", len(series.AltTitles)-maxAltTitles))
+		}
+		lines = append(lines, secondaryStyle.Render("Also known as "+strings.Join(titles, " · ")))
+	}
+
+	facts := []string{}
+	for _, fact := range []string{series.Status, yearLabel(series.Year), series.ContentRating, manga.LanguageName(series.Language)} {
+		if fact != "" {
+			facts = append(facts, fact)
+		}
+	}
+	lines = append(lines, "")
+	if len(facts) > 0 {
+		lines = append(lines, strings.Join(facts, " · "))
+	}
+	lines = appendDetail(lines, "Authors", series.Authors)
+	lines = appendDetail(lines, "Artists", series.Artists)
+	if last := lastChapterLabel(series); last != "" {
+		lines = append(lines, "Last chapter: "+last)
+	}
+	lines = appendDetail(lines, "Genres", series.Genres)
+	lines = appendDetail(lines, "Tags", series.Tags)
+
+	if description := truncateDescription(series.Description); description != "" {
+		lines = append(lines, "", description)
+	}
+
+	if len(series.Links) > 0 {
+		lines = append(lines, "", panelTitleStyle.Render("Links"))
+		for _, link := range series.Links {
+			lines = append(lines, fmt.Sprintf("%s: %s", link.Name, secondaryStyle.Render(link.URL)))
+		}
+	}
+	return lines
+}
+
+func appendDetail(lines []string, label string, values []string) []string {
+	if len(values) == 0 {
+		return lines
+	}
+	return append(lines, fmt.Sprintf("%s: %s", label, strings.Join(values, ", ")))
+}
+
+func yearLabel(year int) string {
+	if year <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d", year)
+}
+
+func lastChapterLabel(series manga.SeriesDetails) string {
+	if series.LastChapter == "" && series.LastVolume == "" {
+		return ""
+	}
+	return manga.FormatChapterLabel(manga.Chapter{Number: series.LastChapter, Volume: series.LastVolume})
+}
+
+func truncateDescription(description string) string {
+	description = strings.TrimSpace(description)
+	runes := []rune(description)
+	if len(runes) <= maxDescriptionLength {
+		return description
+	}
+	return strings.TrimSpace(string(runes[:maxDescriptionLength])) + "…"
+}
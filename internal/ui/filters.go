@@ -0,0 +1,107 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/ssh-vom/boox-serve/internal/config"
+	"github.com/ssh-vom/boox-serve/internal/providers/manga"
+)
+
+const (
+	filterRatings = iota
+	filterStatuses
+	filterDemographics
+	filterLanguages
+	filterTags
+	filterExcludedTags
+	filterYear
+	filterOrder
+	filterCount
+)
+
+func newFilterInputs(cfg config.Config) []textinput.Model {
+	ratings := cfg.Providers.ContentRatings
+	if len(ratings) == 0 {
+		ratings = manga.DefaultContentRatings
+	}
+
+	fields := []struct {
+		prompt      string
+		placeholder string
+		value       string
+	}{
+		filterRatings:      {prompt: "Content rating: ", placeholder: strings.Join(manga.ContentRatings, ","), value: strings.Join(ratings, ",")},
+		filterStatuses:     {prompt: "Status: ", placeholder: strings.Join(manga.Statuses, ",")},
+		filterDemographics: {prompt: "Demographic: ", placeholder: strings.Join(manga.Demographics, ",")},
+		filterLanguages:    {prompt: "Original language: ", placeholder: "ja,ko,zh"},
+		filterTags:         {prompt: "Tags: ", placeholder: "action,romance"},
+		filterExcludedTags: {prompt: "Exclude tags: ", placeholder: "horror"},
+		filterYear:         {prompt: "Year: ", placeholder: "any"},
+		filterOrder:        {prompt: "Sort: ", placeholder: "relevance, latest, popular, rating, newest, title or year", value: string(manga.OrderRelevance)},
+	}
+
+	inputs := make([]textinput.Model, filterCount)
+	for index, field := range fields {
+		input := textinput.New()
+		input.Prompt = field.prompt
+		input.Placeholder = field.placeholder
+		input.SetValue(field.value)
+		input.CharLimit = 200
+		input.PromptStyle = blurStyle
+		input.TextStyle = blurStyle
+		inputs[index] = input
+	}
+	return inputs
+}
+
+func buildSearchFilter(inputs []textinput.Model) (manga.SearchFilter, error) {
+	filter := manga.SearchFilter{
+		ContentRatings:    manga.ParseList(inputs[filterRatings].Value()),
+		Statuses:          manga.ParseList(inputs[filterStatuses].Value()),
+		Demographics:      manga.ParseList(inputs[filterDemographics].Value()),
+		OriginalLanguages: manga.ParseList(inputs[filterLanguages].Value()),
+		IncludedTags:      manga.ParseList(inputs[filterTags].Value()),
+		ExcludedTags:      manga.ParseList(inputs[filterExcludedTags].Value()),
+	}
+
+	year, err := manga.ParseYear(inputs[filterYear].Value())
+	if err != nil {
+		return filter, err
+	}
+	filter.Year = year
+
+	filter.Order, err = manga.ParseSearchOrder(inputs[filterOrder].Value())
+	if err != nil {
+		return filter, err
+	}
+	return filter, filter.Validate()
+}
+
+func (model *model) focusQueryField(focus int) {
+	model.queryFocus = focus
+	if focus == 0 {
+		model.textInput.Focus()
+	} else {
+		model.textInput.Blur()
+	}
+	for index := range model.filterInputs {
+		if index+1 == focus {
+			model.filterInputs[index].Focus()
+			model.filterInputs[index].PromptStyle = focusedStyle
+			model.filterInputs[index].TextStyle = focusedStyle
+		} else {
+			model.filterInputs[index].Blur()
+			model.filterInputs[index].PromptStyle = blurStyle
+			model.filterInputs[index].TextStyle = blurStyle
+		}
+	}
+}
+
+func (model model) filterPanelView() []string {
+	lines := []string{secondaryStyle.Render("Filters")}
+	for _, input := range model.filterInputs {
+		lines = append(lines, input.View())
+	}
+	return lines
+}
@@ -0,0 +1,122 @@
+package ui
+
+import (
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/ssh-vom/boox-serve/internal/config"
+	"github.com/ssh-vom/boox-serve/internal/providers/manga"
+)
+
+type languageItem struct {
+	language manga.Language
+}
+
+func (item languageItem) Title() string       { return item.language.Name }
+func (item languageItem) Description() string { return item.language.Code }
+func (item languageItem) FilterValue() string { return item.language.Name + " " + item.language.Code }
+func (item languageItem) MarkKey() string     { return item.language.Code }
+
+func newLanguageList(current []string, width, height int) (list.Model, map[string]bool) {
+	current = manga.NormalizeLanguages(current)
+	items := make([]list.Item, 0, len(manga.Languages)+len(current))
+	listed := map[string]bool{}
+	for _, code := range current {
+		items = append(items, languageItem{language: manga.Language{Code: code, Name: manga.LanguageName(code)}})
+		listed[code] = true
+	}
+	for _, language := range manga.Languages {
+		if !listed[language.Code] {
+			items = append(items, languageItem{language: language})
+		}
+	}
+
+	selected := make(map[string]bool)
+	for _, code := range current {
+		selected[code] = true
+	}
+	delegate := multiSelectDelegate{selected: selected}
+	languageList := list.New(items, delegate, width, height)
+	languageList.Title = "Chapter languages"
+	languageList.SetShowStatusBar(false)
+	languageList.SetFilteringEnabled(false)
+	languageList.SetShowHelp(false)
+
+	return languageList, selected
+}
+
+func selectedLanguages(items []list.Item, selected map[string]bool) []string {
+	languages := []string{}
+	for _, item := range items {
+		if language, ok := item.(languageItem); ok && selected[language.MarkKey()] {
+			languages = append(languages, language.language.Code)
+		}
+	}
+	return languages
+}
+
+func (model *model) openLanguages() {
+	model.languageList, model.languageMarks = newLanguageList(model.config.Providers.ChapterLanguages, model.width-4, listHeight(model.height))
+	model.errorMessage = ""
+	model.state = stateLanguages
+}
+
+func (model *model) updateLanguages(msg tea.Msg) tea.Cmd {
+	key, ok := msg.(tea.KeyMsg)
+	if ok {
+		switch key.String() {
+		case "esc":
+			model.errorMessage = ""
+			model.state = stateMangaChapters
+			return nil
+		case " ":
+			model.errorMessage = ""
+			toggleMark(model.languageMarks, model.languageList.SelectedItem())
+			return nil
+		case "enter":
+			return model.saveLanguages()
+		}
+	}
+
+	var cmd tea.Cmd
+	model.languageList, cmd = model.languageList.Update(msg)
+	return cmd
+}
+
+func (model *model) saveLanguages() tea.Cmd {
+	languages := selectedLanguages(model.languageList.Items(), model.languageMarks)
+	if len(languages) == 0 {
+		model.errorMessage = "Select at least one language"
+		return nil
+	}
+
+	updated := model.config
+	updated.Providers.ChapterLanguages = languages
+	if err := config.SaveConfig(updated); err != nil {
+		model.errorMessage = err.Error()
+		return nil
+	}
+	model.config = updated
+
+	if model.buildDeps != nil {
+		deps, _ := model.buildDeps(updated)
+		model.applyDependencies(deps)
+	}
+
+	model.errorMessage = ""
+	model.state = stateMangaLoadingChapters
+	return tea.Batch(model.spinner.Tick, fetchChaptersCmd(model.mangaProvider, model.selectedManga.ID))
+}
+
+func (model model) languagesView() string {
+	lines := []string{
+		titleStyle.Render("Chapter Languages"),
+		model.languageList.View(),
+	}
+	if model.errorMessage != "" {
+		lines = append(lines, warningStyle.Render(model.errorMessage))
+	}
+	lines = append(lines, secondaryStyle.Render("Space to toggle · Enter to save and reload chapters · esc to back"))
+	lines = append(lines, secondaryStyle.Render("Earlier languages win; chapters missing in all of them fall back to another language"))
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+}
@@ -0,0 +1,442 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/ssh-vom/boox-serve/internal/boox"
+)
+
+const libraryPageSize = 50
+
+var librarySortFields = []string{"updatedAt", "title", "size"}
+
+type libraryMode int
+
+const (
+	libraryBrowsing libraryMode = iota
+	libraryRenaming
+	libraryCreatingFolder
+	libraryConfirmDelete
+)
+
+type libraryCrumb struct {
+	id    string
+	title string
+}
+
+type libraryModel struct {
+	list       list.Model
+	path       []libraryCrumb
+	offset     int
+	total      int
+	sortIndex  int
+	descending bool
+	mode       libraryMode
+	input      textinput.Model
+	target     boox.LibraryItem
+	loading    bool
+	errorText  string
+	infoText   string
+}
+
+type libraryEntry struct {
+	item boox.LibraryItem
+}
+
+func (entry libraryEntry) Title() string {
+	if entry.item.IsFolder {
+		return entry.item.Title + "/"
+	}
+	return entry.item.Title
+}
+
+func (entry libraryEntry) Description() string {
+	details := []string{}
+	if entry.item.IsFolder {
+		details = append(details, fmt.Sprintf("%d book(s)", entry.item.BookCount))
+	} else if entry.item.Size > 0 {
+		details = append(details, formatBytes(entry.item.Size))
+	}
+	if !entry.item.UpdatedAt.IsZero() {
+		details = append(details, "updated "+entry.item.UpdatedAt.Format("2006-01-02 15:04"))
+	}
+	return strings.Join(details, " · ")
+}
+
+func (entry libraryEntry) FilterValue() string { return entry.item.Title }
+
+type libraryLoadedMsg struct {
+	items []boox.LibraryItem
+	total int
+	err   error
+}
+
+type libraryActionMsg struct {
+	info string
+	err  error
+}
+
+func newLibraryModel(width, height int) libraryModel {
+	libraryList := list.New([]list.Item{}, list.NewDefaultDelegate(), width, height)
+	libraryList.Title = "Library"
+	libraryList.SetShowStatusBar(false)
+	libraryList.SetFilteringEnabled(false)
+	libraryList.SetShowHelp(false)
+
+	return libraryModel{list: libraryList, descending: true}
+}
+
+func (library libraryModel) folderID() string {
+	if len(library.path) == 0 {
+		return ""
+	}
+	return library.path[len(library.path)-1].id
+}
+
+func (library libraryModel) queryParams() boox.LibraryQueryParams {
+	order := "asc"
+	if library.descending {
+		order = "desc"
+	}
+	return boox.LibraryQueryParams{
+		Limit:           libraryPageSize,
+		Offset:          library.offset,
+		SortBy:          librarySortFields[library.sortIndex],
+		Order:           order,
+		LibraryUniqueID: library.folderID(),
+	}
+}
+
+func (library libraryModel) pageCount() int {
+	if library.total <= 0 {
+		return 1
+	}
+	return (library.total + libraryPageSize - 1) / libraryPageSize
+}
+
+func (model *model) openLibrary() tea.Cmd {
+	model.library = newLibraryModel(model.width-4, listHeight(model.height))
+	model.state = stateLibrary
+	return model.reloadLibrary()
+}
+
+func (model *model) reloadLibrary() tea.Cmd {
+	model.library.loading = true
+	return tea.Batch(fetchLibraryCmd(model.booxClient, model.library.queryParams()), model.spinner.Tick)
+}
+
+func (model *model) handleLibraryLoaded(msg libraryLoadedMsg) tea.Cmd {
+	model.library.loading = false
+	if msg.err != nil {
+		model.library.errorText = msg.err.Error()
+		return nil
+	}
+
+	items := make([]list.Item, 0, len(msg.items))
+	for _, item := range msg.items {
+		items = append(items, libraryEntry{item: item})
+	}
+	model.library.total = msg.total
+	model.library.list.Select(0)
+	return model.library.list.SetItems(items)
+}
+
+func (model *model) handleLibraryAction(msg libraryActionMsg) tea.Cmd {
+	if msg.err != nil {
+		model.library.errorText = msg.err.Error()
+		model.library.loading = false
+		return nil
+	}
+	model.library.infoText = msg.info
+	return model.reloadLibrary()
+}
+
+func (model *model) updateLibrary(msg tea.Msg) tea.Cmd {
+	if _, ok := msg.(spinner.TickMsg); ok {
+		if !model.library.loading {
+			return nil
+		}
+		var cmd tea.Cmd
+		model.spinner, cmd = model.spinner.Update(msg)
+		return cmd
+	}
+
+	switch model.library.mode {
+	case libraryRenaming, libraryCreatingFolder:
+		return model.updateLibraryInput(msg)
+	case libraryConfirmDelete:
+		return model.updateLibraryConfirmDelete(msg)
+	}
+
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		var cmd tea.Cmd
+		model.library.list, cmd = model.library.list.Update(msg)
+		return cmd
+	}
+
+	if model.library.loading && key.String() != "esc" && key.String() != "q" {
+		return nil
+	}
+	model.library.errorText = ""
+	model.library.infoText = ""
+
+	selected, hasSelection := model.library.list.SelectedItem().(libraryEntry)
+
+	switch key.String() {
+	case "esc", "backspace":
+		if len(model.library.path) == 0 {
+			if key.String() == "esc" {
+				model.state = stateMenu
+			}
+			return nil
+		}
+		model.library.path = model.library.path[:len(model.library.path)-1]
+		model.library.offset = 0
+		return model.reloadLibrary()
+	case "q":
+		model.state = stateMenu
+		return nil
+	case "enter":
+		if !hasSelection || !selected.item.IsFolder {
+			return nil
+		}
+		if selected.item.ID == "" {
+			model.library.errorText = "Folder has no id"
+			return nil
+		}
+		model.library.path = append(model.library.path, libraryCrumb{id: selected.item.ID, title: selected.item.Title})
+		model.library.offset = 0
+		return model.reloadLibrary()
+	case "]":
+		if model.library.offset+libraryPageSize >= model.library.total {
+			return nil
+		}
+		model.library.offset += libraryPageSize
+		return model.reloadLibrary()
+	case "[":
+		if model.library.offset == 0 {
+			return nil
+		}
+		model.library.offset -= libraryPageSize
+		if model.library.offset < 0 {
+			model.library.offset = 0
+		}
+		return model.reloadLibrary()
+	case "s":
+		model.library.sortIndex = (model.library.sortIndex + 1) % len(librarySortFields)
+		model.library.offset = 0
+		return model.reloadLibrary()
+	case "o":
+		model.library.descending = !model.library.descending
+		model.library.offset = 0
+		return model.reloadLibrary()
+	case "R":
+		return model.reloadLibrary()
+	case "r":
+		if !hasSelection {
+			return nil
+		}
+		model.library.target = selected.item
+		model.library.mode = libraryRenaming
+		model.library.input = newLibraryInput("New name: ", selected.item.Title)
+		return textinput.Blink
+	case "c":
+		model.library.mode = libraryCreatingFolder
+		model.library.input = newLibraryInput("Folder name: ", "")
+		return textinput.Blink
+	case "x", "delete":
+		if !hasSelection {
+			return nil
+		}
+		model.library.target = selected.item
+		model.library.mode = libraryConfirmDelete
+		return nil
+	}
+
+	var cmd tea.Cmd
+	model.library.list, cmd = model.library.list.Update(msg)
+	return cmd
+}
+
+func (model *model) updateLibraryInput(msg tea.Msg) tea.Cmd {
+	key, ok := msg.(tea.KeyMsg)
+	if ok {
+		switch key.String() {
+		case "esc":
+			model.library.mode = libraryBrowsing
+			return nil
+		case "enter":
+			name := strings.TrimSpace(model.library.input.Value())
+			if name == "" {
+				model.library.errorText = "Name cannot be empty"
+				return nil
+			}
+			mode := model.library.mode
+			model.library.mode = libraryBrowsing
+			model.library.errorText = ""
+			model.library.loading = true
+
+			client := model.booxClient
+			if mode == libraryRenaming {
+				target := model.library.target
+				return libraryActionCmd(client, fmt.Sprintf("Renamed to %s", name), func(ctx context.Context) error {
+					return client.RenameItem(ctx, target.ID, name)
+				})
+			}
+
+			var parentID *string
+			if folderID := model.library.folderID(); folderID != "" {
+				parentID = &folderID
+			}
+			return libraryActionCmd(client, fmt.Sprintf("Created folder %s", name), func(ctx context.Context) error {
+				_, err := client.CreateFolder(ctx, parentID, name)
+				return err
+			})
+		}
+	}
+
+	var cmd tea.Cmd
+	model.library.input, cmd = model.library.input.Update(msg)
+	return cmd
+}
+
+func (model *model) updateLibraryConfirmDelete(msg tea.Msg) tea.Cmd {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return nil
+	}
+
+	switch key.String() {
+	case "y", "Y":
+		model.library.mode = libraryBrowsing
+		model.library.loading = true
+		client := model.booxClient
+		target := model.library.target
+		return libraryActionCmd(client, fmt.Sprintf("Deleted %s", target.Title), func(ctx context.Context) error {
+			return client.DeleteItem(ctx, target.ID)
+		})
+	case "n", "N", "esc":
+		model.library.mode = libraryBrowsing
+	}
+
+	return nil
+}
+
+func (model model) libraryView() string {
+	library := model.library
+	crumbs := []string{"Library"}
+	for _, crumb := range library.path {
+		crumbs = append(crumbs, crumb.title)
+	}
+
+	lines := []string{
+		titleStyle.Render("Boox Library"),
+		secondaryStyle.Render(strings.Join(crumbs, " / ")),
+	}
+
+	if library.loading {
+		lines = append(lines, fmt.Sprintf("%s Loading library...", model.spinner.View()))
+	} else {
+		lines = append(lines, library.list.View())
+	}
+
+	order := "asc"
+	if library.descending {
+		order = "desc"
+	}
+	currentPage := library.offset/libraryPageSize + 1
+	lines = append(lines, secondaryStyle.Render(fmt.Sprintf(
+		"Page %d/%d · %d item(s) · sorted by %s (%s)",
+		currentPage,
+		library.pageCount(),
+		library.total,
+		librarySortFields[library.sortIndex],
+		order,
+	)))
+
+	switch library.mode {
+	case libraryRenaming, libraryCreatingFolder:
+		lines = append(lines, library.input.View())
+	case libraryConfirmDelete:
+		lines = append(lines, warningStyle.Render(fmt.Sprintf("Delete %s? (y/n)", library.target.Title)))
+	}
+
+	if library.errorText != "" {
+		lines = append(lines, warningStyle.Render(library.errorText))
+	}
+	if library.infoText != "" {
+		lines = append(lines, secondaryStyle.Render(library.infoText))
+	}
+
+	help := "Enter open · backspace up · [/] page · s sort · o order · r rename · c new folder · x delete · R refresh · esc back"
+	if library.mode != libraryBrowsing {
+		help = "Enter to confirm · esc to cancel"
+	}
+	lines = append(lines, secondaryStyle.Render(help))
+
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+}
+
+func newLibraryInput(prompt, value string) textinput.Model {
+	input := textinput.New()
+	input.Prompt = prompt
+	input.CharLimit = 200
+	input.SetValue(value)
+	input.Focus()
+	return input
+}
+
+func fetchLibraryCmd(client *boox.Client, params boox.LibraryQueryParams) tea.Cmd {
+	return func() tea.Msg {
+		if client == nil {
+			return libraryLoadedMsg{err: errors.New("boox device not configured")}
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		defer cancel()
+		response, err := client.GetLibrary(ctx, params)
+		if err != nil {
+			return libraryLoadedMsg{err: err}
+		}
+		return libraryLoadedMsg{
+			items: response.Items(),
+			total: response.BookCount + response.LibraryCount,
+		}
+	}
+}
+
+func libraryActionCmd(client *boox.Client, info string, action func(ctx context.Context) error) tea.Cmd {
+	return func() tea.Msg {
+		if client == nil {
+			return libraryActionMsg{err: errors.New("boox device not configured")}
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		defer cancel()
+		if err := action(ctx); err != nil {
+			return libraryActionMsg{err: err}
+		}
+		return libraryActionMsg{info: info}
+	}
+}
+
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "KMGTPE"[exp])
+}
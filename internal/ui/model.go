@@ -7,6 +7,7 @@ import (
 	"io"
 	"log"
 	"math"
+	"net/http"
 	"os"
 	"strconv"
 	"strings"
@@ -22,7 +23,9 @@ import (
 	"github.com/ssh-vom/boox-serve/internal/boox"
 	"github.com/ssh-vom/boox-serve/internal/config"
 	"github.com/ssh-vom/boox-serve/internal/cover"
+	"github.com/ssh-vom/boox-serve/internal/imaging"
 	"github.com/ssh-vom/boox-serve/internal/providers/manga"
+	"github.com/ssh-vom/boox-serve/internal/providers/textbooks"
 )
 
 type appState int
@@ -40,8 +43,13 @@ const (
 	stateDownloadDone
 	stateSettings
 	stateAbout
-	stateTextbooks
+	stateTextbookQuery
+	stateTextbookSearching
+	stateTextbookResults
 	stateLibrary
+	stateQueue
+	stateLanguages
+	stateMangaDetails
 )
 
 type menuItem struct {
@@ -68,12 +76,49 @@ func (item mangaResultItem) Description() string {
 func (item mangaResultItem) FilterValue() string { return item.result.Title }
 
 type chapterItem struct {
-	chapter manga.Chapter
+	chapter  manga.Chapter
+	onDevice bool
 }
 
-func (item chapterItem) Title() string       { return manga.FormatChapterLabel(item.chapter) }
-func (item chapterItem) Description() string { return "" }
+func (item chapterItem) Title() string { return manga.FormatChapterLabel(item.chapter) }
+func (item chapterItem) Description() string {
+	details := []string{}
+	if item.chapter.Language != "" {
+		details = append(details, item.chapter.Language)
+	}
+	if item.chapter.Group != "" {
+		details = append(details, item.chapter.Group)
+	}
+	if item.chapter.Pages > 0 {
+		details = append(details, fmt.Sprintf("%dp", item.chapter.Pages))
+	}
+	if !item.chapter.PublishedAt.IsZero() {
+		details = append(details, item.chapter.PublishedAt.Format("2006-01-02"))
+	}
+	if item.onDevice {
+		details = append(details, "on device")
+	}
+	return strings.Join(details, " · ")
+}
 func (item chapterItem) FilterValue() string { return manga.FormatChapterLabel(item.chapter) }
+func (item chapterItem) MarkKey() string     { return item.chapter.ID }
+
+type textbookItem struct {
+	result textbooks.Result
+}
+
+func (item textbookItem) Title() string { return item.result.Title }
+func (item textbookItem) Description() string {
+	details := []string{}
+	for _, value := range []string{item.result.Author, item.result.Publisher, item.result.Extension, item.result.Size} {
+		if strings.TrimSpace(value) != "" {
+			details = append(details, value)
+		}
+	}
+	return strings.Join(details, " · ")
+}
+func (item textbookItem) FilterValue() string { return item.result.Title + " " + item.result.Author }
+func (item textbookItem) MarkKey() string     { return item.result.Hash }
 
 type connectionResultMsg struct {
 	device *boox.DeviceDetails
@@ -81,8 +126,10 @@ type connectionResultMsg struct {
 }
 
 type mangaSearchMsg struct {
-	results []manga.SearchResult
-	err     error
+	search int
+	page   manga.SearchPage
+	offset int
+	err    error
 }
 
 type chaptersMsg struct {
@@ -90,8 +137,20 @@ type chaptersMsg struct {
 	err      error
 }
 
+type deviceChaptersMsg struct {
+	mangaID string
+	present map[string]bool
+	err     error
+}
+
+type textbookSearchMsg struct {
+	results []textbooks.Result
+	err     error
+}
+
 type downloadStartMsg struct {
 	updates <-chan app.ProgressUpdate
+	cancel  context.CancelFunc
 }
 
 type coverLoadedMsg struct {
@@ -107,19 +166,47 @@ type logMsg string
 type model struct {
 	state appState
 
-	config        config.Config
-	booxClient    *boox.Client
-	mangaProvider manga.Provider
-	buildDeps     BuildDependencies
+	config           config.Config
+	booxClient       *boox.Client
+	sink             app.Sink
+	deviceModel      string
+	mangaProvider    manga.Provider
+	textbookProvider textbooks.Provider
+	httpClient       *http.Client
+	queue            *app.Queue
+	buildDeps        BuildDependencies
 
 	menu         list.Model
 	textInput    textinput.Model
+	filterInputs []textinput.Model
+	queryFocus   int
 	resultsList  list.Model
 	chapterList  list.Model
-	chapterMarks map[int]bool
+	chapterMarks map[string]bool
+
+	textbookList  list.Model
+	textbookMarks map[string]bool
+
+	languageList  list.Model
+	languageMarks map[string]bool
+
+	library libraryModel
+
+	queueList   list.Model
+	activeJobID string
 
-	selectedManga manga.SearchResult
-	chapters      []manga.Chapter
+	searchQuery       string
+	searchFilter      manga.SearchFilter
+	searchGeneration  int
+	searchPage        manga.SearchPage
+	searchLoadingMore bool
+
+	selectedManga  manga.SearchResult
+	seriesDetails  manga.SeriesDetails
+	detailsLoading bool
+	allChapters    []manga.Chapter
+	chapters       []manga.Chapter
+	mangaOptions   app.MangaUploadOptions
 
 	coverCache           map[string]cover.Image
 	coverErrors          map[string]string
@@ -130,12 +217,20 @@ type model struct {
 	coverTransitionTotal int
 	supportsGraphics     bool
 
-	progress        progress.Model
-	progressCurrent int
-	progressTotal   int
-	progressMessage string
-	downloadErr     error
-	downloadUpdates <-chan app.ProgressUpdate
+	progress           progress.Model
+	progressCurrent    int
+	progressTotal      int
+	progressPages      int
+	progressPagesTotal int
+	progressBytes      int64
+	progressBytesTotal int64
+	progressMessage    string
+	downloadErr        error
+	doneMessage        string
+	downloadSummary    *app.DownloadSummary
+	downloadUpdates    <-chan app.ProgressUpdate
+	downloadCancel     context.CancelFunc
+	cancelling         bool
 
 	spinner spinner.Model
 
@@ -160,17 +255,22 @@ type settingsModel struct {
 }
 
 type Dependencies struct {
-	BooxClient    *boox.Client
-	MangaProvider manga.Provider
+	BooxClient       *boox.Client
+	Sink             app.Sink
+	MangaProvider    manga.Provider
+	TextbookProvider textbooks.Provider
+	HTTPClient       *http.Client
+	Queue            *app.Queue
 }
 
 type BuildDependencies func(cfg config.Config) (Dependencies, error)
 
 func NewModel(cfg config.Config, deps Dependencies, buildDeps BuildDependencies, startupErr error) model {
 	menu := newMenuList(0, 0)
-	textInput := newQueryInput()
+	textInput := newQueryInput(mangaQueryPlaceholder)
 	resultsList := list.New([]list.Item{}, list.NewDefaultDelegate(), 0, 0)
 	chapterList := list.New([]list.Item{}, list.NewDefaultDelegate(), 0, 0)
+	textbookList := list.New([]list.Item{}, list.NewDefaultDelegate(), 0, 0)
 
 	spinnerModel := spinner.New()
 	spinnerModel.Spinner = spinner.Dot
@@ -181,13 +281,21 @@ func NewModel(cfg config.Config, deps Dependencies, buildDeps BuildDependencies,
 		state:                stateChecking,
 		config:               cfg,
 		booxClient:           deps.BooxClient,
+		sink:                 deps.Sink,
 		mangaProvider:        deps.MangaProvider,
+		textbookProvider:     deps.TextbookProvider,
+		httpClient:           deps.HTTPClient,
+		queue:                deps.Queue,
+		queueList:            newQueueList(0, 0),
 		buildDeps:            buildDeps,
 		menu:                 menu,
 		textInput:            textInput,
+		filterInputs:         newFilterInputs(cfg),
 		resultsList:          resultsList,
 		chapterList:          chapterList,
-		chapterMarks:         map[int]bool{},
+		chapterMarks:         map[string]bool{},
+		textbookList:         textbookList,
+		textbookMarks:        map[string]bool{},
 		coverCache:           map[string]cover.Image{},
 		coverErrors:          map[string]string{},
 		coverLoadingURL:      "",
@@ -199,6 +307,7 @@ func NewModel(cfg config.Config, deps Dependencies, buildDeps BuildDependencies,
 		spinner:              spinnerModel,
 		progress:             progressModel,
 		verbose:              cfg.Verbose,
+		mangaOptions:         app.MangaUploadOptions{Existing: app.ExistingSkip, Bundle: app.BundleChapter, Format: app.OutputCBZ},
 	}
 
 	if startupErr != nil {
@@ -223,6 +332,9 @@ func (model model) Init() tea.Cmd {
 	if model.verbose {
 		commands = append(commands, listenLogCmd(model.logChannel))
 	}
+	if model.queue != nil {
+		commands = append(commands, listenQueueCmd(model.queue))
+	}
 	return tea.Batch(commands...)
 }
 
@@ -233,6 +345,10 @@ func (model model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		model.height = msg.Height
 		model.menu.SetSize(msg.Width-4, listHeight(msg.Height))
 		model.chapterList.SetSize(msg.Width-4, listHeight(msg.Height))
+		model.textbookList.SetSize(msg.Width-4, listHeight(msg.Height))
+		model.library.list.SetSize(msg.Width-4, listHeight(msg.Height))
+		model.queueList.SetSize(msg.Width-4, listHeight(msg.Height))
+		model.languageList.SetSize(msg.Width-4, listHeight(msg.Height))
 		if model.state == stateMangaResults {
 			model.resultsList.SetSize(resultsListWidth(msg.Width), listHeight(msg.Height))
 		} else {
@@ -261,15 +377,24 @@ func (model model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return model, nil
 		}
 		model.state = stateMenu
+		model.deviceModel = msg.device.Model
 		model.infoMessage = fmt.Sprintf("Connected to %s", msg.device.Model)
 		return model, nil
 	case mangaSearchMsg:
+		if msg.search != model.searchGeneration {
+			return model, nil
+		}
+		if msg.offset > 0 {
+			return model, model.appendSearchPage(msg)
+		}
 		if msg.err != nil {
 			model.state = stateMangaQuery
 			model.errorMessage = msg.err.Error()
 			return model, nil
 		}
-		model.resultsList = newMangaResultsList(msg.results, resultsListWidth(model.width), listHeight(model.height))
+		model.searchPage = msg.page
+		model.searchLoadingMore = false
+		model.resultsList = newMangaResultsList(msg.page.Results, resultsListWidth(model.width), listHeight(model.height))
 		model.coverCache = map[string]cover.Image{}
 		model.coverErrors = map[string]string{}
 		model.coverLoadingURL = ""
@@ -279,23 +404,51 @@ func (model model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		model.coverTransitionTotal = 0
 		model.state = stateMangaResults
 		return model, model.requestCoverCmd()
+	case seriesDetailsMsg:
+		model.handleSeriesDetails(msg)
+		return model, nil
 	case chaptersMsg:
 		if msg.err != nil {
-			model.state = stateMangaResults
+			model.state = stateMangaDetails
 			model.errorMessage = msg.err.Error()
 			return model, nil
 		}
-		model.chapters = msg.chapters
-		model.chapterList, model.chapterMarks = newChapterList(msg.chapters, model.width, model.height)
+		model.allChapters = msg.chapters
 		model.state = stateMangaChapters
+		return model, model.showChapters()
+	case deviceChaptersMsg:
+		if msg.err != nil || msg.mangaID != model.selectedManga.ID {
+			return model, nil
+		}
+		return model, model.markDeviceChapters(msg.present)
+	case textbookSearchMsg:
+		if msg.err != nil {
+			model.state = stateTextbookQuery
+			model.errorMessage = msg.err.Error()
+			return model, nil
+		}
+		if len(msg.results) == 0 {
+			model.state = stateTextbookQuery
+			model.errorMessage = "No textbooks found"
+			return model, nil
+		}
+		model.textbookList, model.textbookMarks = newTextbookList(msg.results, model.width, model.height)
+		model.state = stateTextbookResults
 		return model, nil
+	case libraryLoadedMsg:
+		return model, model.handleLibraryLoaded(msg)
+	case libraryActionMsg:
+		return model, model.handleLibraryAction(msg)
 	case downloadStartMsg:
 		model.state = stateDownloading
+		model.activeJobID = ""
 		model.downloadErr = nil
+		model.doneMessage = ""
+		model.downloadSummary = nil
 		model.downloadUpdates = msg.updates
-		model.progressCurrent = 0
-		model.progressTotal = 0
-		model.progressMessage = "Starting download"
+		model.downloadCancel = msg.cancel
+		model.cancelling = false
+		model.resetProgress("Starting download")
 		return model, listenProgressCmd(model.downloadUpdates)
 	case app.ProgressUpdate:
 		if msg.Err != nil {
@@ -303,16 +456,20 @@ func (model model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		if msg.Done {
 			model.state = stateDownloadDone
+			model.downloadSummary = msg.Summary
+			if model.downloadCancel != nil {
+				model.downloadCancel()
+				model.downloadCancel = nil
+			}
+			if errors.Is(model.downloadErr, context.Canceled) {
+				model.downloadErr = nil
+				model.doneMessage = "Download cancelled."
+			}
 			return model, nil
 		}
-		model.progressCurrent = msg.Current
-		model.progressTotal = msg.Total
-		model.progressMessage = msg.Message
-		var progressCmd tea.Cmd
-		if msg.Total > 0 {
-			progressCmd = model.progress.SetPercent(float64(msg.Current) / float64(msg.Total))
-		}
-		return model, tea.Batch(progressCmd, listenProgressCmd(model.downloadUpdates))
+		return model, tea.Batch(model.applyProgress(msg), listenProgressCmd(model.downloadUpdates))
+	case queueEventMsg:
+		return model, model.handleQueueEvent(msg)
 	case progress.FrameMsg:
 		updatedModel, cmd := model.progress.Update(msg)
 		if progressModel, ok := updatedModel.(progress.Model); ok {
@@ -360,6 +517,89 @@ func (model model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return model.handleStateUpdate(msg)
 }
 
+func (model *model) resetProgress(message string) {
+	model.progressCurrent = 0
+	model.progressTotal = 0
+	model.progressPages = 0
+	model.progressPagesTotal = 0
+	model.progressBytes = 0
+	model.progressBytesTotal = 0
+	model.progressMessage = message
+}
+
+func (model *model) cancelDownload() {
+	if model.cancelling {
+		return
+	}
+	switch {
+	case model.activeJobID != "" && model.queue != nil:
+		if err := model.queue.Cancel(model.activeJobID); err != nil {
+			model.progressMessage = err.Error()
+			return
+		}
+	case model.downloadCancel != nil:
+		model.downloadCancel()
+	default:
+		return
+	}
+	model.cancelling = true
+}
+
+func downloadSummaryLines(summary *app.DownloadSummary) []string {
+	if summary == nil {
+		return nil
+	}
+
+	lines := []string{}
+	if len(summary.Uploaded) > 0 {
+		lines = append(lines, fmt.Sprintf("Uploaded %d chapter(s): %s", len(summary.Uploaded), chapterNumbers(summary.Uploaded)))
+	}
+	if len(summary.Existing) > 0 {
+		lines = append(lines, secondaryStyle.Render(fmt.Sprintf("Already on device %d chapter(s): %s", len(summary.Existing), chapterNumbers(summary.Existing))))
+	}
+	if len(summary.Skipped) > 0 {
+		lines = append(lines, warningStyle.Render(fmt.Sprintf("Skipped %d chapter(s): %s", len(summary.Skipped), chapterNumbers(summary.Skipped))))
+	}
+	if len(summary.Aborted) > 0 {
+		lines = append(lines, warningStyle.Render(fmt.Sprintf("Not uploaded %d chapter(s): %s", len(summary.Aborted), chapterNumbers(summary.Aborted))))
+	}
+	return lines
+}
+
+func chapterNumbers(chapters []manga.Chapter) string {
+	const maxListed = 12
+
+	numbers := []string{}
+	for index, chapter := range chapters {
+		if index == maxListed {
+			numbers = append(numbers, fmt.Sprintf("… (+%d more)", len(chapters)-maxListed))
+			break
+		}
+		if chapter.Number != "" {
+			numbers = append(numbers, chapter.Number)
+		} else {
+			numbers = append(numbers, manga.FormatChapterLabel(chapter))
+		}
+	}
+	return strings.Join(numbers, ", ")
+}
+
+func (model *model) applyProgress(update app.ProgressUpdate) tea.Cmd {
+	model.progressCurrent = update.Current
+	model.progressTotal = update.Total
+	model.progressPages = update.PagesDone
+	model.progressPagesTotal = update.PagesTotal
+	model.progressBytes = update.BytesDone
+	model.progressBytesTotal = update.BytesTotal
+	if update.Message != "" {
+		model.progressMessage = update.Message
+	}
+	if update.Total > 0 {
+		return model.progress.SetPercent(float64(update.Current) / float64(update.Total))
+	}
+	return nil
+}
+
 func (model *model) handleStateUpdate(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch model.state {
 	case stateChecking:
@@ -385,6 +625,15 @@ func (model *model) handleStateUpdate(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case stateMangaChapters:
 		return *model, model.updateMangaChapters(msg)
 	case stateDownloading:
+		if key, ok := msg.(tea.KeyMsg); ok {
+			switch {
+			case key.String() == "esc" && model.activeJobID != "":
+				return *model, model.openQueue()
+			case key.String() == "x":
+				model.cancelDownload()
+				return *model, nil
+			}
+		}
 		spinnerCmd := model.spinner.Tick
 		model.spinner, spinnerCmd = model.spinner.Update(msg)
 		return *model, spinnerCmd
@@ -392,7 +641,23 @@ func (model *model) handleStateUpdate(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return *model, model.updateDownloadDone(msg)
 	case stateSettings:
 		return *model, model.updateSettings(msg)
-	case stateAbout, stateTextbooks, stateLibrary:
+	case stateTextbookQuery:
+		return *model, model.updateTextbookQuery(msg)
+	case stateTextbookSearching:
+		spinnerCmd := model.spinner.Tick
+		model.spinner, spinnerCmd = model.spinner.Update(msg)
+		return *model, spinnerCmd
+	case stateTextbookResults:
+		return *model, model.updateTextbookResults(msg)
+	case stateLibrary:
+		return *model, model.updateLibrary(msg)
+	case stateQueue:
+		return *model, model.updateQueue(msg)
+	case stateLanguages:
+		return *model, model.updateLanguages(msg)
+	case stateMangaDetails:
+		return *model, model.updateMangaDetails(msg)
+	case stateAbout:
 		return *model, model.updateInfoScreens(msg)
 	default:
 		return *model, nil
@@ -418,10 +683,11 @@ func (model model) View() string {
 			titleStyle.Render("Search Manga"),
 			model.textInput.View(),
 		}
+		lines = append(lines, model.filterPanelView()...)
 		if model.errorMessage != "" {
 			lines = append(lines, warningStyle.Render(model.errorMessage))
 		}
-		lines = append(lines, secondaryStyle.Render("Enter to search · esc to cancel"))
+		lines = append(lines, secondaryStyle.Render("Enter to search · tab to edit filters · esc to cancel"))
 		view = lipgloss.JoinVertical(lipgloss.Left, lines...)
 	case stateMangaSearching:
 		view = fmt.Sprintf("%s Searching MangaDex...", model.spinner.View())
@@ -437,28 +703,58 @@ func (model model) View() string {
 		if model.errorMessage != "" {
 			lines = append(lines, warningStyle.Render(model.errorMessage))
 		}
-		lines = append(lines, secondaryStyle.Render("Space to toggle · Enter to download · esc to back"))
+		lines = append(lines, secondaryStyle.Render(fmt.Sprintf(
+			"Space to toggle · Enter to download · m already on device: %s · b bundle: %s · f format: %s · s spreads: %s · c crop: %s · l languages: %s · d duplicates: %s · g prefer group · esc to back",
+			model.mangaOptions.Existing,
+			model.mangaOptions.BundleLabel(),
+			model.mangaOptions.Format.Label(),
+			model.seriesSpreads(),
+			onOff(model.config.ImagesForSeries(model.selectedManga.ID).Crop),
+			strings.Join(manga.NormalizeLanguages(model.config.Providers.ChapterLanguages), ","),
+			model.dedupeLabel(),
+		)))
 		view = lipgloss.JoinVertical(lipgloss.Left, lines...)
 	case stateDownloading:
 		progressLine := model.progress.View()
 		if model.progressTotal > 0 {
 			progressLine = fmt.Sprintf("%s %d/%d", progressLine, model.progressCurrent, model.progressTotal)
 		}
-		view = lipgloss.JoinVertical(lipgloss.Left,
+		lines := []string{
 			titleStyle.Render("Downloading"),
-			model.spinner.View()+" "+model.progressMessage,
+			model.spinner.View() + " " + model.progressMessage,
 			progressLine,
-		)
+		}
+		if model.progressPagesTotal > 0 {
+			lines = append(lines, secondaryStyle.Render(fmt.Sprintf("Pages %d/%d", model.progressPages, model.progressPagesTotal)))
+		}
+		if model.progressBytes > 0 {
+			transferred := formatBytes(model.progressBytes)
+			if model.progressBytesTotal > 0 {
+				transferred = fmt.Sprintf("%s / %s", transferred, formatBytes(model.progressBytesTotal))
+			}
+			lines = append(lines, secondaryStyle.Render("Transferred "+transferred))
+		}
+		switch {
+		case model.cancelling:
+			lines = append(lines, warningStyle.Render("Cancelling..."))
+		case model.activeJobID != "":
+			lines = append(lines, secondaryStyle.Render("x to cancel · esc to keep running in the background"))
+		case model.downloadCancel != nil:
+			lines = append(lines, secondaryStyle.Render("x to cancel"))
+		}
+		view = lipgloss.JoinVertical(lipgloss.Left, lines...)
 	case stateDownloadDone:
 		message := "Download complete."
+		if model.doneMessage != "" {
+			message = model.doneMessage
+		}
 		if model.downloadErr != nil {
 			message = "Download completed with errors:\n" + model.downloadErr.Error()
 		}
-		view = lipgloss.JoinVertical(lipgloss.Left,
-			titleStyle.Render("Done"),
-			message,
-			secondaryStyle.Render("Press enter to return"),
-		)
+		lines := []string{titleStyle.Render("Done"), message}
+		lines = append(lines, downloadSummaryLines(model.downloadSummary)...)
+		lines = append(lines, secondaryStyle.Render("Press enter to return"))
+		view = lipgloss.JoinVertical(lipgloss.Left, lines...)
 	case stateSettings:
 		view = model.settingsView()
 	case stateAbout:
@@ -467,18 +763,36 @@ func (model model) View() string {
 			"Boox Uploader TUI for manga and textbooks.",
 			secondaryStyle.Render("Press esc to go back"),
 		)
-	case stateTextbooks:
-		view = lipgloss.JoinVertical(lipgloss.Left,
-			titleStyle.Render("Textbook Search"),
-			"Textbook sources (LibGen, Anna's Archive) are coming soon.",
-			secondaryStyle.Render("Press esc to go back"),
-		)
+	case stateTextbookQuery:
+		lines := []string{
+			titleStyle.Render("Search Textbooks"),
+			model.textInput.View(),
+		}
+		if model.errorMessage != "" {
+			lines = append(lines, warningStyle.Render(model.errorMessage))
+		}
+		lines = append(lines, secondaryStyle.Render("Enter to search · esc to cancel"))
+		view = lipgloss.JoinVertical(lipgloss.Left, lines...)
+	case stateTextbookSearching:
+		view = fmt.Sprintf("%s Searching LibGen...", model.spinner.View())
+	case stateTextbookResults:
+		lines := []string{
+			titleStyle.Render("Select Textbooks"),
+			model.textbookList.View(),
+		}
+		if model.errorMessage != "" {
+			lines = append(lines, warningStyle.Render(model.errorMessage))
+		}
+		lines = append(lines, secondaryStyle.Render("Space to toggle · Enter to download · esc to back"))
+		view = lipgloss.JoinVertical(lipgloss.Left, lines...)
 	case stateLibrary:
-		view = lipgloss.JoinVertical(lipgloss.Left,
-			titleStyle.Render("Boox Library"),
-			"Library browsing will be available in a follow-up.",
-			secondaryStyle.Render("Press esc to go back"),
-		)
+		view = model.libraryView()
+	case stateQueue:
+		view = model.queueView()
+	case stateLanguages:
+		view = model.languagesView()
+	case stateMangaDetails:
+		view = model.mangaDetailsView()
 	}
 
 	if model.verbose {
@@ -529,9 +843,19 @@ func (model *model) updateMenu(msg tea.Msg) tea.Cmd {
 				model.state = stateSettings
 				return nil
 			}
+			if selected.action == stateLibrary {
+				return model.openLibrary()
+			}
+			if selected.action == stateQueue {
+				return model.openQueue()
+			}
 			model.state = selected.action
-			if selected.action == stateMangaQuery {
-				model.textInput = newQueryInput()
+			switch selected.action {
+			case stateMangaQuery:
+				model.textInput = newQueryInput(mangaQueryPlaceholder)
+				model.focusQueryField(0)
+			case stateTextbookQuery:
+				model.textInput = newQueryInput(textbookQueryPlaceholder)
 				model.textInput.Focus()
 			}
 			return nil
@@ -557,18 +881,34 @@ func (model *model) updateMangaQuery(msg tea.Msg) tea.Cmd {
 	if ok && key.String() != "enter" {
 		model.errorMessage = ""
 	}
+	if ok && (key.String() == "tab" || key.String() == "shift+tab") {
+		model.focusQueryField(updateSettingsFocus(key.String(), model.queryFocus, len(model.filterInputs)+1))
+		return nil
+	}
 
 	var cmd tea.Cmd
-	model.textInput, cmd = model.textInput.Update(msg)
+	if model.queryFocus == 0 {
+		model.textInput, cmd = model.textInput.Update(msg)
+	} else {
+		current := &model.filterInputs[model.queryFocus-1]
+		*current, cmd = current.Update(msg)
+	}
 	if ok && key.String() == "enter" {
+		filter, err := buildSearchFilter(model.filterInputs)
+		if err != nil {
+			model.errorMessage = err.Error()
+			return nil
+		}
 		query := strings.TrimSpace(model.textInput.Value())
-		if query == "" {
+		if query == "" && !filter.Active() {
 			model.errorMessage = "Search query cannot be empty"
 			return nil
 		}
 		model.state = stateMangaSearching
 		model.errorMessage = ""
-		return searchMangaCmd(model.mangaProvider, query)
+		model.searchQuery, model.searchFilter = query, filter
+		model.searchGeneration++
+		return searchMangaCmd(model.mangaProvider, model.searchGeneration, query, filter, 0)
 	}
 
 	return cmd
@@ -578,6 +918,7 @@ func (model *model) updateMangaResults(msg tea.Msg) tea.Cmd {
 	var cmd tea.Cmd
 	model.resultsList, cmd = model.resultsList.Update(msg)
 	model.errorMessage = ""
+	cmd = tea.Batch(cmd, model.loadMoreResults())
 
 	transitionCmd := tea.Cmd(nil)
 	selectedURL := model.selectedCoverURL()
@@ -601,9 +942,7 @@ func (model *model) updateMangaResults(msg tea.Msg) tea.Cmd {
 		return nil
 	case "enter":
 		if selected, ok := model.resultsList.SelectedItem().(mangaResultItem); ok {
-			model.selectedManga = selected.result
-			model.state = stateMangaLoadingChapters
-			return fetchChaptersCmd(model.mangaProvider, selected.result.ID)
+			return model.openMangaDetails(selected.result)
 		}
 	}
 
@@ -612,25 +951,55 @@ func (model *model) updateMangaResults(msg tea.Msg) tea.Cmd {
 
 func (model *model) updateMangaChapters(msg tea.Msg) tea.Cmd {
 	key, ok := msg.(tea.KeyMsg)
-	if ok {
+	if ok && model.chapterList.FilterState() != list.Filtering {
 		switch key.String() {
 		case "esc":
-			model.state = stateMangaResults
+			model.state = stateMangaDetails
 			return nil
 		case " ":
 			model.errorMessage = ""
-			index := model.chapterList.Index()
-			model.chapterMarks[index] = !model.chapterMarks[index]
+			toggleMark(model.chapterMarks, model.chapterList.SelectedItem())
+			return nil
+		case "m":
+			model.mangaOptions.Existing = model.mangaOptions.Existing.Next()
+			return nil
+		case "b":
+			model.mangaOptions = model.mangaOptions.NextBundle()
+			return fetchDeviceChaptersCmd(model.sink, model.selectedManga, model.chapters, model.uploadOptions())
+		case "f":
+			model.mangaOptions.Format = model.mangaOptions.Format.Next()
+			return fetchDeviceChaptersCmd(model.sink, model.selectedManga, model.chapters, model.uploadOptions())
+		case "s":
+			model.cycleSeriesSpreads()
+			return nil
+		case "c":
+			model.toggleSeriesCrop()
 			return nil
+		case "l":
+			model.openLanguages()
+			return nil
+		case "d":
+			return model.cycleDedupe()
+		case "g":
+			return model.togglePreferredGroup()
 		case "enter":
 			selected := selectedChapters(model.chapterList.Items(), model.chapterMarks)
 			if len(selected) == 0 {
 				model.errorMessage = "Select at least one chapter"
 				return nil
 			}
+			if model.queue == nil {
+				model.errorMessage = "Download queue unavailable"
+				return nil
+			}
+			job, err := model.queue.Enqueue(model.selectedManga.ID, model.selectedManga.Title, selected, model.uploadOptions())
+			if err != nil {
+				model.errorMessage = err.Error()
+				return nil
+			}
 			model.errorMessage = ""
-			model.state = stateDownloading
-			return startDownloadCmd(model.booxClient, model.mangaProvider, model.selectedManga.Title, selected)
+			model.followJob(job)
+			return model.spinner.Tick
 		}
 	}
 
@@ -639,6 +1008,62 @@ func (model *model) updateMangaChapters(msg tea.Msg) tea.Cmd {
 	return cmd
 }
 
+func (model *model) updateTextbookQuery(msg tea.Msg) tea.Cmd {
+	key, ok := msg.(tea.KeyMsg)
+	if ok && key.String() == "esc" {
+		model.state = stateMenu
+		model.errorMessage = ""
+		return nil
+	}
+	if ok && key.String() != "enter" {
+		model.errorMessage = ""
+	}
+
+	var cmd tea.Cmd
+	model.textInput, cmd = model.textInput.Update(msg)
+	if ok && key.String() == "enter" {
+		query := strings.TrimSpace(model.textInput.Value())
+		if query == "" {
+			model.errorMessage = "Search query cannot be empty"
+			return nil
+		}
+		model.state = stateTextbookSearching
+		model.errorMessage = ""
+		return searchTextbooksCmd(model.textbookProvider, query)
+	}
+
+	return cmd
+}
+
+func (model *model) updateTextbookResults(msg tea.Msg) tea.Cmd {
+	key, ok := msg.(tea.KeyMsg)
+	if ok && model.textbookList.FilterState() != list.Filtering {
+		switch key.String() {
+		case "esc":
+			model.errorMessage = ""
+			model.state = stateTextbookQuery
+			return nil
+		case " ":
+			model.errorMessage = ""
+			toggleMark(model.textbookMarks, model.textbookList.SelectedItem())
+			return nil
+		case "enter":
+			selected := selectedTextbooks(model.textbookList.Items(), model.textbookMarks)
+			if len(selected) == 0 {
+				model.errorMessage = "Select at least one textbook"
+				return nil
+			}
+			model.errorMessage = ""
+			model.state = stateDownloading
+			return startTextbookDownloadCmd(model.sink, model.httpClient, selected)
+		}
+	}
+
+	var cmd tea.Cmd
+	model.textbookList, cmd = model.textbookList.Update(msg)
+	return cmd
+}
+
 func (model *model) updateDownloadDone(msg tea.Msg) tea.Cmd {
 	key, ok := msg.(tea.KeyMsg)
 	if ok && (key.String() == "enter" || key.String() == "esc") {
@@ -707,8 +1132,7 @@ func (model *model) saveSettings() tea.Cmd {
 			model.settings.errorText = err.Error()
 			return nil
 		}
-		model.booxClient = deps.BooxClient
-		model.mangaProvider = deps.MangaProvider
+		model.applyDependencies(deps)
 	}
 
 	model.settings.errorText = ""
@@ -720,6 +1144,14 @@ func (model *model) saveSettings() tea.Cmd {
 	return nil
 }
 
+func (model *model) applyDependencies(deps Dependencies) {
+	model.booxClient = deps.BooxClient
+	model.sink = deps.Sink
+	model.mangaProvider = deps.MangaProvider
+	model.textbookProvider = deps.TextbookProvider
+	model.httpClient = deps.HTTPClient
+}
+
 func (model *model) updateInfoScreens(msg tea.Msg) tea.Cmd {
 	key, ok := msg.(tea.KeyMsg)
 	if ok && (key.String() == "esc" || key.String() == "q") {
@@ -758,6 +1190,9 @@ func (model model) mangaResultsView() string {
 	if model.errorMessage != "" {
 		listSection = append(listSection, warningStyle.Render(model.errorMessage))
 	}
+	if status := model.searchStatus(); status != "" {
+		listSection = append(listSection, secondaryStyle.Render(status))
+	}
 	listSection = append(listSection, secondaryStyle.Render("Enter to select · esc to cancel"))
 
 	selected := manga.SearchResult{}
@@ -939,8 +1374,9 @@ func (model model) mangaCoverPanel(result manga.SearchResult, width int) string
 func newMenuList(width, height int) list.Model {
 	items := []list.Item{
 		menuItem{title: "Search Manga", description: "Find manga and upload chapters", action: stateMangaQuery},
-		menuItem{title: "Search Textbooks", description: "LibGen and Anna's Archive", action: stateTextbooks},
-		menuItem{title: "Boox Library", description: "Browse titles on device", action: stateLibrary},
+		menuItem{title: "Search Textbooks", description: "Find textbooks on LibGen", action: stateTextbookQuery},
+		menuItem{title: "Boox Library", description: "Browse, rename and organise titles on device", action: stateLibrary},
+		menuItem{title: "Download Queue", description: "Pause, reorder, retry or cancel downloads", action: stateQueue},
 		menuItem{title: "Settings", description: "Edit Boox connection", action: stateSettings},
 		menuItem{title: "About/Help", description: "Usage and shortcuts", action: stateAbout},
 	}
@@ -954,14 +1390,61 @@ func newMenuList(width, height int) list.Model {
 	return menu
 }
 
-func newQueryInput() textinput.Model {
+const (
+	mangaQueryPlaceholder    = "e.g. One Piece"
+	textbookQueryPlaceholder = "e.g. Linear Algebra Done Right"
+)
+
+func newQueryInput(placeholder string) textinput.Model {
 	input := textinput.New()
-	input.Placeholder = "e.g. One Piece"
+	input.Placeholder = placeholder
 	input.Focus()
 	input.Prompt = "> "
 	return input
 }
 
+func (model *model) loadMoreResults() tea.Cmd {
+	if model.searchLoadingMore || !model.searchPage.HasMore() || model.resultsList.FilterState() != list.Unfiltered {
+		return nil
+	}
+	if items := model.resultsList.Items(); len(items) > 0 && model.resultsList.Index() < len(items)-1 {
+		return nil
+	}
+	model.searchLoadingMore = true
+	return searchMangaCmd(model.mangaProvider, model.searchGeneration, model.searchQuery, model.searchFilter, model.searchPage.Next)
+}
+
+func (model *model) appendSearchPage(msg mangaSearchMsg) tea.Cmd {
+	model.searchLoadingMore = false
+	if msg.offset != model.searchPage.Next {
+		return nil
+	}
+	if msg.err != nil {
+		model.errorMessage = msg.err.Error()
+		return nil
+	}
+
+	model.searchPage = msg.page
+	items := model.resultsList.Items()
+	for _, result := range msg.page.Results {
+		items = append(items, mangaResultItem{result: result})
+	}
+	return tea.Batch(model.resultsList.SetItems(items), model.loadMoreResults(), model.requestCoverCmd())
+}
+
+func (model model) searchStatus() string {
+	count := len(model.resultsList.Items())
+	switch {
+	case model.searchLoadingMore:
+		return fmt.Sprintf("%d of %d · loading more...", count, model.searchPage.Total)
+	case model.searchPage.HasMore():
+		return fmt.Sprintf("%d of %d · scroll to the end for more", count, model.searchPage.Total)
+	case count > 0:
+		return fmt.Sprintf("%d results", count)
+	}
+	return ""
+}
+
 func newMangaResultsList(results []manga.SearchResult, width, height int) list.Model {
 	items := make([]list.Item, 0, len(results))
 	for _, result := range results {
@@ -977,13 +1460,13 @@ func newMangaResultsList(results []manga.SearchResult, width, height int) list.M
 	return resultList
 }
 
-func newChapterList(chapters []manga.Chapter, width, height int) (list.Model, map[int]bool) {
+func newChapterList(chapters []manga.Chapter, width, height int) (list.Model, map[string]bool) {
 	items := make([]list.Item, 0, len(chapters))
 	for _, chapter := range chapters {
 		items = append(items, chapterItem{chapter: chapter})
 	}
 
-	selected := make(map[int]bool)
+	selected := make(map[string]bool)
 	delegate := multiSelectDelegate{selected: selected}
 	chapterList := list.New(items, delegate, width, height)
 	chapterList.Title = "Chapters"
@@ -994,14 +1477,168 @@ func newChapterList(chapters []manga.Chapter, width, height int) (list.Model, ma
 	return chapterList, selected
 }
 
-func selectedChapters(items []list.Item, selected map[int]bool) []manga.Chapter {
-	chapters := []manga.Chapter{}
-	for index, item := range items {
-		if selected[index] {
-			chapterItem, ok := item.(chapterItem)
-			if !ok {
-				continue
+func newTextbookList(results []textbooks.Result, width, height int) (list.Model, map[string]bool) {
+	items := make([]list.Item, 0, len(results))
+	for _, result := range results {
+		items = append(items, textbookItem{result: result})
+	}
+
+	selected := make(map[string]bool)
+	delegate := multiSelectDelegate{selected: selected}
+	textbookList := list.New(items, delegate, width, height)
+	textbookList.Title = "Textbooks"
+	textbookList.SetShowStatusBar(false)
+	textbookList.SetFilteringEnabled(true)
+	textbookList.SetShowHelp(false)
+
+	return textbookList, selected
+}
+
+func selectedTextbooks(items []list.Item, selected map[string]bool) []app.TitleAndHash {
+	books := []app.TitleAndHash{}
+	for _, item := range items {
+		textbook, ok := item.(textbookItem)
+		if !ok || !selected[textbook.MarkKey()] {
+			continue
+		}
+		books = append(books, app.TitleAndHash{
+			Title:     textbook.result.Title,
+			Hash:      textbook.result.Hash,
+			Extension: textbook.result.Extension,
+		})
+	}
+	return books
+}
+
+func (model model) uploadOptions() app.MangaUploadOptions {
+	options := model.mangaOptions
+	options.FolderTemplate = model.config.MangaFolder
+	options.CoverURL = model.selectedManga.CoverURL
+	options.Images = model.config.ImagesForSeries(model.selectedManga.ID).ForDevice(model.deviceModel)
+	return options
+}
+
+func (model model) seriesSpreads() imaging.SpreadMode {
+	mode, err := imaging.ParseSpreadMode(string(model.config.ImagesForSeries(model.selectedManga.ID).Spreads))
+	if err != nil {
+		return imaging.SpreadsOff
+	}
+	return mode
+}
+
+func (model *model) cycleSeriesSpreads() {
+	next := model.seriesSpreads().Next()
+	model.updateSeries(func(series *config.SeriesConfig) {
+		series.Spreads = next
+	})
+}
+
+func (model *model) toggleSeriesCrop() {
+	crop := !model.config.ImagesForSeries(model.selectedManga.ID).Crop
+	model.updateSeries(func(series *config.SeriesConfig) {
+		series.Crop = &crop
+	})
+}
+
+func onOff(enabled bool) string {
+	if enabled {
+		return "on"
+	}
+	return "off"
+}
+
+func (model *model) updateSeries(update func(*config.SeriesConfig)) {
+	updated := model.config
+	updated.Series = make(map[string]config.SeriesConfig, len(model.config.Series)+1)
+	for id, series := range model.config.Series {
+		updated.Series[id] = series
+	}
+
+	series := updated.Series[model.selectedManga.ID]
+	series.Title = model.selectedManga.Title
+	update(&series)
+	updated.Series[model.selectedManga.ID] = series
+
+	if err := config.SaveConfig(updated); err != nil {
+		model.errorMessage = err.Error()
+		return
+	}
+	model.config = updated
+}
+
+func (model *model) showChapters() tea.Cmd {
+	marked := model.chapterMarks
+	model.chapters = model.config.ChaptersForSeries(model.selectedManga.ID, model.allChapters)
+	model.chapterList, model.chapterMarks = newChapterList(model.chapters, model.width, model.height)
+	for _, chapter := range model.chapters {
+		if marked[chapter.ID] {
+			model.chapterMarks[chapter.ID] = true
+		}
+	}
+	return fetchDeviceChaptersCmd(model.sink, model.selectedManga, model.chapters, model.uploadOptions())
+}
+
+func (model model) dedupeLabel() string {
+	label := "all uploads"
+	if mode, _ := manga.ParseDedupeMode(string(model.config.Providers.Dedupe)); mode == manga.DedupeBest {
+		label = "best upload"
+	}
+	if groups := model.config.Series[model.selectedManga.ID].Groups; len(groups) > 0 {
+		label += " (prefer " + strings.Join(groups, ", ") + ")"
+	}
+	return label
+}
+
+func (model *model) cycleDedupe() tea.Cmd {
+	updated := model.config
+	updated.Providers.Dedupe = updated.Providers.Dedupe.Next()
+	if err := config.SaveConfig(updated); err != nil {
+		model.errorMessage = err.Error()
+		return nil
+	}
+	model.config = updated
+	return model.showChapters()
+}
+
+func (model *model) togglePreferredGroup() tea.Cmd {
+	item, ok := model.chapterList.SelectedItem().(chapterItem)
+	if !ok || item.chapter.Group == "" {
+		model.errorMessage = "Chapter has no scanlation group"
+		return nil
+	}
+
+	group := item.chapter.Group
+	model.updateSeries(func(series *config.SeriesConfig) {
+		first := len(series.Groups) > 0 && strings.EqualFold(series.Groups[0], group)
+		groups := []string{}
+		if !first {
+			groups = append(groups, group)
+		}
+		for _, existing := range series.Groups {
+			if !strings.EqualFold(existing, group) {
+				groups = append(groups, existing)
 			}
+		}
+		series.Groups = groups
+	})
+	return model.showChapters()
+}
+
+func (model *model) markDeviceChapters(present map[string]bool) tea.Cmd {
+	items := model.chapterList.Items()
+	for index, item := range items {
+		if chapter, ok := item.(chapterItem); ok {
+			chapter.onDevice = present[chapter.chapter.ID]
+			items[index] = chapter
+		}
+	}
+	return model.chapterList.SetItems(items)
+}
+
+func selectedChapters(items []list.Item, selected map[string]bool) []manga.Chapter {
+	chapters := []manga.Chapter{}
+	for _, item := range items {
+		if chapterItem, ok := item.(chapterItem); ok && selected[chapterItem.MarkKey()] {
 			chapters = append(chapters, chapterItem.chapter)
 		}
 	}
@@ -1020,15 +1657,15 @@ func checkConnectionCmd(client *boox.Client) tea.Cmd {
 	}
 }
 
-func searchMangaCmd(provider manga.Provider, query string) tea.Cmd {
+func searchMangaCmd(provider manga.Provider, search int, query string, filter manga.SearchFilter, offset int) tea.Cmd {
 	return func() tea.Msg {
 		if provider == nil {
-			return mangaSearchMsg{err: errors.New("manga provider unavailable")}
+			return mangaSearchMsg{search: search, offset: offset, err: errors.New("manga provider unavailable")}
 		}
 		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
 		defer cancel()
-		results, err := provider.Search(ctx, query)
-		return mangaSearchMsg{results: results, err: err}
+		page, err := provider.Search(ctx, query, filter, offset)
+		return mangaSearchMsg{search: search, page: page, offset: offset, err: err}
 	}
 }
 
@@ -1044,6 +1681,18 @@ func fetchChaptersCmd(provider manga.Provider, mangaID string) tea.Cmd {
 	}
 }
 
+func fetchDeviceChaptersCmd(sink app.Sink, selected manga.SearchResult, chapters []manga.Chapter, options app.MangaUploadOptions) tea.Cmd {
+	return func() tea.Msg {
+		if sink == nil {
+			return deviceChaptersMsg{mangaID: selected.ID, err: errors.New("output target not configured")}
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		defer cancel()
+		present, err := app.ExistingChapters(ctx, sink, selected.Title, chapters, options)
+		return deviceChaptersMsg{mangaID: selected.ID, present: present, err: err}
+	}
+}
+
 func fetchCoverCmd(provider manga.Provider, coverURL string) tea.Cmd {
 	return func() tea.Msg {
 		if coverURL == "" {
@@ -1078,26 +1727,38 @@ func fetchCoverCmd(provider manga.Provider, coverURL string) tea.Cmd {
 	}
 }
 
-func startDownloadCmd(booxClient *boox.Client, provider manga.Provider, mangaTitle string, chapters []manga.Chapter) tea.Cmd {
+func searchTextbooksCmd(provider textbooks.Provider, query string) tea.Cmd {
+	return func() tea.Msg {
+		if provider == nil {
+			return textbookSearchMsg{err: errors.New("textbook provider unavailable")}
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+		defer cancel()
+		results, err := provider.Search(ctx, query)
+		return textbookSearchMsg{results: results, err: err}
+	}
+}
+
+func startTextbookDownloadCmd(sink app.Sink, httpClient *http.Client, items []app.TitleAndHash) tea.Cmd {
 	return func() tea.Msg {
-		updates := make(chan app.ProgressUpdate, len(chapters)+2)
+		ctx, cancel := context.WithCancel(context.Background())
+		updates := make(chan app.ProgressUpdate, len(items)+2)
 		go func() {
-			if booxClient == nil {
-				updates <- app.ProgressUpdate{Done: true, Err: errors.New("boox connection unavailable")}
+			if sink == nil {
+				updates <- app.ProgressUpdate{Done: true, Err: errors.New("output target unavailable")}
 				close(updates)
 				return
 			}
-			if provider == nil {
-				updates <- app.ProgressUpdate{Done: true, Err: errors.New("manga provider unavailable")}
+			if httpClient == nil {
+				updates <- app.ProgressUpdate{Done: true, Err: errors.New("http client unavailable")}
 				close(updates)
 				return
 			}
-			ctx := context.Background()
-			err := app.DownloadAndUploadMangaChapters(ctx, booxClient, provider, mangaTitle, chapters, updates)
+			err := app.DownloadAndUploadLibGen(ctx, sink, httpClient, items, updates)
 			updates <- app.ProgressUpdate{Done: true, Err: err}
 			close(updates)
 		}()
-		return downloadStartMsg{updates: updates}
+		return downloadStartMsg{updates: updates, cancel: cancel}
 	}
 }
 
@@ -1203,7 +1864,7 @@ func applySettingsFocus(settings settingsModel) settingsModel {
 }
 
 func newSettingsModel(cfg config.Config) settingsModel {
-	inputs := make([]textinput.Model, 3)
+	inputs := make([]textinput.Model, 4)
 
 	urlInput := textinput.New()
 	urlInput.Prompt = "Boox URL: "
@@ -1222,9 +1883,16 @@ func newSettingsModel(cfg config.Config) settingsModel {
 	}
 	portInput.CharLimit = 6
 
+	folderInput := textinput.New()
+	folderInput.Prompt = "Manga folder: "
+	folderInput.Placeholder = app.DefaultFolderTemplate
+	folderInput.SetValue(cfg.MangaFolder)
+	folderInput.CharLimit = 200
+
 	inputs[0] = urlInput
 	inputs[1] = ipInput
 	inputs[2] = portInput
+	inputs[3] = folderInput
 
 	settings := settingsModel{inputs: inputs, focus: 0}
 	return applySettingsFocus(settings)
@@ -1234,6 +1902,7 @@ func buildConfigFromSettings(cfg config.Config, inputs []textinput.Model) (confi
 	urlValue := strings.TrimSpace(inputs[0].Value())
 	ipValue := strings.TrimSpace(inputs[1].Value())
 	portValue := strings.TrimSpace(inputs[2].Value())
+	folderValue := strings.Trim(strings.TrimSpace(inputs[3].Value()), "/")
 
 	if urlValue == "" && ipValue == "" {
 		return cfg, errors.New("boox url or ip is required")
@@ -1241,6 +1910,7 @@ func buildConfigFromSettings(cfg config.Config, inputs []textinput.Model) (confi
 
 	cfg.BooxURL = urlValue
 	cfg.BooxIP = ipValue
+	cfg.MangaFolder = folderValue
 
 	if portValue != "" {
 		port, err := strconv.Atoi(portValue)
@@ -1253,8 +1923,19 @@ func buildConfigFromSettings(cfg config.Config, inputs []textinput.Model) (confi
 	return cfg, nil
 }
 
+type markableItem interface {
+	MarkKey() string
+}
+
+func toggleMark(marks map[string]bool, item list.Item) {
+	if markable, ok := item.(markableItem); ok {
+		key := markable.MarkKey()
+		marks[key] = !marks[key]
+	}
+}
+
 type multiSelectDelegate struct {
-	selected map[int]bool
+	selected map[string]bool
 }
 
 func (delegate multiSelectDelegate) Height() int                                   { return 1 }
@@ -1263,7 +1944,7 @@ func (delegate multiSelectDelegate) Update(msg tea.Msg, model *list.Model) tea.C
 
 func (delegate multiSelectDelegate) Render(writer io.Writer, model list.Model, index int, item list.Item) {
 	checkbox := " "
-	if delegate.selected[index] {
+	if markable, ok := item.(markableItem); ok && delegate.selected[markable.MarkKey()] {
 		checkbox = "x"
 	}
 	cursor := " "
@@ -1274,6 +1955,9 @@ func (delegate multiSelectDelegate) Render(writer io.Writer, model list.Model, i
 	if titled, ok := item.(interface{ Title() string }); ok {
 		title = titled.Title()
 	}
+	if described, ok := item.(interface{ Description() string }); ok && described.Description() != "" {
+		title = title + " " + secondaryStyle.Render(described.Description())
+	}
 	fmt.Fprintf(writer, "%s [%s] %s", cursor, checkbox, title)
 }
 
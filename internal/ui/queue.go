@@ -0,0 +1,201 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/ssh-vom/boox-serve/internal/app"
+)
+
+type queueEventMsg app.QueueEvent
+
+type queueJobItem struct {
+	job app.Job
+}
+
+func (item queueJobItem) Title() string {
+	return fmt.Sprintf("%s (%d/%d chapters)", item.job.Title, len(item.job.CompletedChapters), len(item.job.Chapters))
+}
+
+func (item queueJobItem) Description() string {
+	details := []string{string(item.job.Status)}
+	switch item.job.Status {
+	case app.JobRunning:
+		if item.job.Progress.Message != "" {
+			details = append(details, item.job.Progress.Message)
+		}
+	case app.JobFailed:
+		if item.job.Error != "" {
+			details = append(details, item.job.Error)
+		}
+	}
+	return strings.Join(details, " · ")
+}
+
+func (item queueJobItem) FilterValue() string { return item.job.Title }
+
+func newQueueList(width, height int) list.Model {
+	queueList := list.New([]list.Item{}, list.NewDefaultDelegate(), width, height)
+	queueList.Title = "Jobs"
+	queueList.SetShowStatusBar(false)
+	queueList.SetFilteringEnabled(false)
+	queueList.SetShowHelp(false)
+	return queueList
+}
+
+func (model *model) openQueue() tea.Cmd {
+	model.state = stateQueue
+	model.errorMessage = ""
+	return model.refreshQueueList()
+}
+
+func (model *model) refreshQueueList() tea.Cmd {
+	if model.queue == nil {
+		return nil
+	}
+
+	jobs := model.queue.Jobs()
+	items := make([]list.Item, 0, len(jobs))
+	for _, job := range jobs {
+		items = append(items, queueJobItem{job: job})
+	}
+	return model.queueList.SetItems(items)
+}
+
+func (model *model) handleQueueEvent(msg queueEventMsg) tea.Cmd {
+	commands := []tea.Cmd{listenQueueCmd(model.queue)}
+	if model.state == stateQueue {
+		commands = append(commands, model.refreshQueueList())
+	}
+
+	if msg.JobID == model.activeJobID && model.state == stateDownloading {
+		if msg.Update.Done {
+			model.state = stateDownloadDone
+			model.downloadErr = msg.Update.Err
+			model.downloadSummary = msg.Update.Summary
+			model.doneMessage = ""
+			model.cancelling = false
+			for _, job := range model.queue.Jobs() {
+				if job.ID != msg.JobID {
+					continue
+				}
+				switch job.Status {
+				case app.JobPaused:
+					model.doneMessage = "Download paused. Resume it from the download queue."
+				case app.JobCancelled:
+					model.doneMessage = "Download cancelled."
+				}
+			}
+			return tea.Batch(commands...)
+		}
+		commands = append(commands, model.applyProgress(msg.Update))
+	}
+
+	return tea.Batch(commands...)
+}
+
+func (model *model) updateQueue(msg tea.Msg) tea.Cmd {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		var cmd tea.Cmd
+		model.queueList, cmd = model.queueList.Update(msg)
+		return cmd
+	}
+
+	model.errorMessage = ""
+	selected, hasSelection := model.queueList.SelectedItem().(queueJobItem)
+
+	var err error
+	switch key.String() {
+	case "esc", "q":
+		model.state = stateMenu
+		return nil
+	case "enter":
+		if hasSelection && selected.job.Status == app.JobRunning {
+			model.followJob(selected.job)
+		}
+		return nil
+	case "p":
+		if !hasSelection {
+			return nil
+		}
+		if selected.job.Status == app.JobPaused {
+			err = model.queue.Resume(selected.job.ID)
+		} else {
+			err = model.queue.Pause(selected.job.ID)
+		}
+	case "r":
+		if hasSelection {
+			err = model.queue.Retry(selected.job.ID)
+		}
+	case "x":
+		if hasSelection {
+			err = model.queue.Cancel(selected.job.ID)
+		}
+	case "K", "shift+up":
+		if hasSelection {
+			err = model.queue.Move(selected.job.ID, -1)
+			if err == nil {
+				model.queueList.CursorUp()
+			}
+		}
+	case "J", "shift+down":
+		if hasSelection {
+			err = model.queue.Move(selected.job.ID, 1)
+			if err == nil {
+				model.queueList.CursorDown()
+			}
+		}
+	case "c":
+		err = model.queue.ClearFinished()
+	default:
+		var cmd tea.Cmd
+		model.queueList, cmd = model.queueList.Update(msg)
+		return cmd
+	}
+
+	if err != nil {
+		model.errorMessage = err.Error()
+	}
+	return model.refreshQueueList()
+}
+
+func (model *model) followJob(job app.Job) {
+	model.activeJobID = job.ID
+	model.state = stateDownloading
+	model.downloadErr = nil
+	model.doneMessage = ""
+	model.downloadSummary = nil
+	model.downloadCancel = nil
+	model.cancelling = false
+	model.resetProgress("Waiting in queue")
+	model.applyProgress(job.Progress)
+}
+
+func (model model) queueView() string {
+	lines := []string{
+		titleStyle.Render("Download Queue"),
+	}
+	if len(model.queueList.Items()) == 0 {
+		lines = append(lines, secondaryStyle.Render("No queued downloads."))
+	} else {
+		lines = append(lines, model.queueList.View())
+	}
+	if model.errorMessage != "" {
+		lines = append(lines, warningStyle.Render(model.errorMessage))
+	}
+	lines = append(lines, secondaryStyle.Render("Enter to follow · p pause/resume · r retry · x cancel · K/J reorder · c clear finished · esc back"))
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+}
+
+func listenQueueCmd(queue *app.Queue) tea.Cmd {
+	if queue == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		return queueEventMsg(<-queue.Events())
+	}
+}